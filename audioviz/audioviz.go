@@ -0,0 +1,93 @@
+// Package audioviz renders audio as waveform and spectrogram images, for
+// looking at recordings whose transcription came out wrong.
+//
+// Waveforms can be rendered as PNG or SVG and spectrograms as PNG. Either
+// can be overlaid with the boundaries of transcript segments.
+package audioviz
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"github.com/tmc/audioutil/transcript"
+)
+
+// Options configures rendering. Zero fields take the defaults noted below.
+type Options struct {
+	Width  int // Image width in pixels (default 1200)
+	Height int // Image height in pixels (default 300)
+
+	// SampleRate is the sample rate of the audio (default 16000).
+	SampleRate int
+
+	// Segments, if set, are overlaid as lines at their start and end
+	// times.
+	Segments []transcript.Segment
+}
+
+func (o Options) withDefaults() Options {
+	if o.Width <= 0 {
+		o.Width = 1200
+	}
+	if o.Height <= 0 {
+		o.Height = 300
+	}
+	if o.SampleRate <= 0 {
+		o.SampleRate = 16000
+	}
+	return o
+}
+
+var (
+	backgroundColor = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	peakColor       = color.RGBA{0x9e, 0xc5, 0xe8, 0xff}
+	rmsColor        = color.RGBA{0x1f, 0x77, 0xb4, 0xff}
+	axisColor       = color.RGBA{0xcc, 0xcc, 0xcc, 0xff}
+	segmentColor    = color.RGBA{0xd6, 0x27, 0x28, 0xff}
+)
+
+// boundary is the x coordinate of the start or end of a segment.
+type boundary struct {
+	x   int
+	seg int // index into Options.Segments
+}
+
+// boundaries returns the segment boundaries that fall within an image
+// showing n samples.
+func (o Options) boundaries(n int) []boundary {
+	if n == 0 {
+		return nil
+	}
+	duration := time.Duration(n) * time.Second / time.Duration(o.SampleRate)
+	var bs []boundary
+	for i, s := range o.Segments {
+		for _, t := range []time.Duration{s.Start, s.End} {
+			x := int(int64(t) * int64(o.Width) / int64(duration))
+			if x >= 0 && x < o.Width {
+				bs = append(bs, boundary{x: x, seg: i})
+			}
+		}
+	}
+	return bs
+}
+
+// drawSegments draws the boundaries of the segments onto img, which shows
+// n samples.
+func drawSegments(img *image.RGBA, opts Options, n int) {
+	for _, b := range opts.boundaries(n) {
+		for row := 0; row < opts.Height; row++ {
+			img.SetRGBA(b.x, row, segmentColor)
+		}
+	}
+}
+
+// fill sets every pixel of img to c.
+func fill(img *image.RGBA, c color.RGBA) {
+	b := img.Bounds()
+	for row := b.Min.Y; row < b.Max.Y; row++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			img.SetRGBA(x, row, c)
+		}
+	}
+}
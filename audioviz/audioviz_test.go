@@ -0,0 +1,96 @@
+package audioviz
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tmc/audioutil/transcript"
+)
+
+func TestSpectrogramTone(t *testing.T) {
+	const (
+		sampleRate = 16000
+		frameSize  = 512
+		freq       = 2000
+	)
+	data := make([]float32, sampleRate)
+	for i := range data {
+		data[i] = float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/sampleRate))
+	}
+	// With one row per bin, the tone's bin k is drawn in row height-1-k.
+	height := frameSize/2 + 1
+	gray := func(v float64) color.RGBA {
+		c := uint8(math.Max(0, math.Min(1, v)) * 255)
+		return color.RGBA{c, c, c, 0xff}
+	}
+	img := Spectrogram(data, SpectrogramOptions{
+		Options:   Options{Width: 10, Height: height, SampleRate: sampleRate},
+		FrameSize: frameSize,
+		Colormap:  gray,
+	})
+	want := height - 1 - freq*frameSize/sampleRate
+	for x := 1; x < 9; x++ {
+		best, bestV := -1, -1
+		for row := 0; row < height; row++ {
+			if v := int(img.RGBAAt(x, row).R); v > bestV {
+				best, bestV = row, v
+			}
+		}
+		if best != want {
+			t.Errorf("column %d: loudest row %d, want %d", x, best, want)
+		}
+	}
+}
+
+func TestSpectrogramMel(t *testing.T) {
+	data := make([]float32, 16000)
+	for i := range data {
+		data[i] = float32(0.5 * math.Sin(2*math.Pi*500*float64(i)/16000))
+	}
+	img := Spectrogram(data, SpectrogramOptions{Options: Options{Width: 20, Height: 80}, Mel: true})
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 80 {
+		t.Errorf("image is %dx%d, want 20x80", b.Dx(), b.Dy())
+	}
+}
+
+func TestWaveformSVG(t *testing.T) {
+	data := []float32{0, 0.5, -0.5, 1, -1, 0.25, 0, 0}
+	var b bytes.Buffer
+	err := WriteWaveformSVG(&b, data, Options{
+		Width: 4, Height: 5, SampleRate: 8,
+		Segments: []transcript.Segment{{Start: 500 * time.Millisecond, End: 750 * time.Millisecond, Text: " a < b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `<svg xmlns="http://www.w3.org/2000/svg" width="4" height="5" viewBox="0 0 4 5">
+<rect width="100%" height="100%" fill="#ffffff"/>
+<line x1="0" y1="2" x2="4" y2="2" stroke="#cccccc"/>
+<polygon fill="#9ec5e8" points="0,1 1,0 2,2 3,2 3,2 2,4 1,3 0,2 "/>
+<polygon fill="#1f77b4" points="0,1 1,0 2,1 3,2 3,2 2,3 1,4 0,3 "/>
+<line x1="2" y1="0" x2="2" y2="5" stroke="#d62728"><title>a &lt; b</title></line>
+<line x1="3" y1="0" x2="3" y2="5" stroke="#d62728"><title>a &lt; b</title></line>
+</svg>
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestWaveform(t *testing.T) {
+	img := Waveform([]float32{1, -1, 0, 0}, Options{Width: 2, Height: 5})
+	for row := 0; row < 5; row++ {
+		if c := img.RGBAAt(0, row); c != peakColor && c != rmsColor {
+			t.Errorf("row %d of the full-scale column is %v, want peak or RMS", row, c)
+		}
+	}
+	if c := img.RGBAAt(1, 0); c != backgroundColor {
+		t.Errorf("top of the silent column is %v, want background", c)
+	}
+	if c := img.RGBAAt(1, 2); c != rmsColor {
+		t.Errorf("middle of the silent column is %v, want the RMS band", c)
+	}
+}
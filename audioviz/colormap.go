@@ -0,0 +1,65 @@
+package audioviz
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Colormap maps a value in [0, 1] to a color.
+type Colormap func(v float64) color.RGBA
+
+// Colormaps for spectrograms.
+var (
+	Gray    = gradient(0x000000, 0xffffff)
+	Hot     = gradient(0x000000, 0xe00000, 0xffd000, 0xffffff)
+	Viridis = gradient(0x440154, 0x472d7b, 0x3b528b, 0x2c728e, 0x21918c, 0x28ae80, 0x5ec962, 0xaddc30, 0xfde725)
+	Magma   = gradient(0x000004, 0x180f3d, 0x440f76, 0x721f81, 0x9e2f7f, 0xcd4071, 0xf1605d, 0xfd9668, 0xfeca8d, 0xfcfdbf)
+)
+
+var colormaps = map[string]Colormap{
+	"gray":    Gray,
+	"hot":     Hot,
+	"viridis": Viridis,
+	"magma":   Magma,
+}
+
+// ColormapNames returns the names accepted by ParseColormap.
+func ColormapNames() []string {
+	var names []string
+	for name := range colormaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseColormap returns the named colormap.
+func ParseColormap(name string) (Colormap, error) {
+	if c, ok := colormaps[strings.ToLower(name)]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown colormap %q (want one of %s)", name, strings.Join(ColormapNames(), ", "))
+}
+
+// gradient returns a colormap that interpolates linearly between evenly
+// spaced stops, given as 0xRRGGBB.
+func gradient(stops ...uint32) Colormap {
+	return func(v float64) color.RGBA {
+		v = math.Max(0, math.Min(1, v))
+		pos := v * float64(len(stops)-1)
+		i := int(pos)
+		if i >= len(stops)-1 {
+			i = len(stops) - 2
+		}
+		f := pos - float64(i)
+		lerp := func(shift uint) uint8 {
+			a := float64(stops[i] >> shift & 0xff)
+			b := float64(stops[i+1] >> shift & 0xff)
+			return uint8(math.Round(a + f*(b-a)))
+		}
+		return color.RGBA{lerp(16), lerp(8), lerp(0), 0xff}
+	}
+}
@@ -0,0 +1,131 @@
+package audioviz
+
+import (
+	"image"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/tmc/audioutil/dsp"
+)
+
+// SpectrogramOptions configures spectrogram rendering. Zero fields take the
+// defaults noted below.
+type SpectrogramOptions struct {
+	Options
+
+	FrameSize int // FFT size in samples (default 512)
+	Hop       int // Samples between frames (default FrameSize/4)
+
+	// Mel selects a mel frequency scale with Mels bands (default 80)
+	// instead of a linear one.
+	Mel  bool
+	Mels int
+
+	// Colormap colors the levels (default Viridis).
+	Colormap Colormap
+	// Range is the dynamic range shown, in dB below the loudest level
+	// (default 80).
+	Range float64
+}
+
+func (o SpectrogramOptions) withDefaults() SpectrogramOptions {
+	o.Options = o.Options.withDefaults()
+	if o.FrameSize <= 0 {
+		o.FrameSize = 512
+	}
+	if o.Hop <= 0 {
+		o.Hop = o.FrameSize / 4
+	}
+	if o.Mels <= 0 {
+		o.Mels = 80
+	}
+	if o.Colormap == nil {
+		o.Colormap = Viridis
+	}
+	if o.Range <= 0 {
+		o.Range = 80
+	}
+	return o
+}
+
+// Spectrogram renders data as a spectrogram, with time running left to
+// right and frequency bottom to top. Frames are analyzed one column at a
+// time, so memory use does not grow with the length of data.
+func Spectrogram(data []float32, opts SpectrogramOptions) *image.RGBA {
+	opts = opts.withDefaults()
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	window := dsp.Hann(opts.FrameSize)
+	var filters [][]float64
+	if opts.Mel {
+		filters = dsp.MelFilterbank(opts.SampleRate, opts.FrameSize, opts.Mels)
+	}
+	// The frames of dsp.STFT: centered on multiples of Hop.
+	frames := len(data)/opts.Hop + 1
+	buf := make([]float64, opts.FrameSize)
+
+	// Average the frames that fall in each column, in dB.
+	levels := make([][]float64, opts.Width)
+	max := math.Inf(-1)
+	for x := range levels {
+		lo := x * frames / opts.Width
+		hi := (x + 1) * frames / opts.Width
+		if hi <= lo {
+			hi = lo + 1
+		}
+		var sum []float64
+		for t := lo; t < hi; t++ {
+			power := framePower(data, t*opts.Hop-opts.FrameSize/2, window, buf)
+			if filters != nil {
+				power = dsp.MelSpectrogram([][]float64{power}, filters)[0]
+			}
+			if sum == nil {
+				sum = make([]float64, len(power))
+			}
+			for k, v := range power {
+				sum[k] += v
+			}
+		}
+		for k, v := range sum {
+			db := 10 * math.Log10(v/float64(hi-lo)+1e-12)
+			sum[k] = db
+			max = math.Max(max, db)
+		}
+		levels[x] = sum
+	}
+	bins := len(levels[0])
+
+	for x, col := range levels {
+		for row := 0; row < opts.Height; row++ {
+			k := (opts.Height - 1 - row) * bins / opts.Height
+			v := (col[k] - (max - opts.Range)) / opts.Range
+			img.SetRGBA(x, row, opts.Colormap(v))
+		}
+	}
+	drawSegments(img, opts.Options, len(data))
+	return img
+}
+
+// framePower returns the power in each bin of the frame of data that starts
+// at sample start, with zeros beyond the ends of data. buf holds the
+// windowed frame.
+func framePower(data []float32, start int, window, buf []float64) []float64 {
+	for i := range buf {
+		buf[i] = 0
+		if j := start + i; j >= 0 && j < len(data) {
+			buf[i] = float64(data[j]) * window[i]
+		}
+	}
+	spec := dsp.RealFFT(buf)
+	power := make([]float64, len(spec))
+	for k, c := range spec {
+		power[k] = real(c)*real(c) + imag(c)*imag(c)
+	}
+	return power
+}
+
+// WriteSpectrogramPNG renders data as a spectrogram and writes it to w as
+// PNG.
+func WriteSpectrogramPNG(w io.Writer, data []float32, opts SpectrogramOptions) error {
+	return png.Encode(w, Spectrogram(data, opts))
+}
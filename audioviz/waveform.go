@@ -0,0 +1,127 @@
+package audioviz
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+)
+
+// column summarizes the samples drawn in one column of a waveform.
+type column struct {
+	min, max, rms float64
+}
+
+// columns summarizes data in width columns.
+func columns(data []float32, width int) []column {
+	cols := make([]column, width)
+	if len(data) == 0 {
+		return cols
+	}
+	for x := range cols {
+		lo := x * len(data) / width
+		hi := (x + 1) * len(data) / width
+		if hi <= lo {
+			hi = lo + 1
+		}
+		c := column{min: math.Inf(1), max: math.Inf(-1)}
+		var sum float64
+		for _, s := range data[lo:hi] {
+			v := float64(s)
+			c.min = math.Min(c.min, v)
+			c.max = math.Max(c.max, v)
+			sum += v * v
+		}
+		c.rms = math.Sqrt(sum / float64(hi-lo))
+		cols[x] = c
+	}
+	return cols
+}
+
+// y maps a sample value in [-1, 1] to a row of an image of the given height.
+func y(v float64, height int) int {
+	v = math.Max(-1, math.Min(1, v))
+	return int(math.Round((1 - v) / 2 * float64(height-1)))
+}
+
+// Waveform renders data as a waveform. The light band shows the peaks of
+// the signal and the dark band its RMS level.
+func Waveform(data []float32, opts Options) *image.RGBA {
+	opts = opts.withDefaults()
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	fill(img, backgroundColor)
+	mid := y(0, opts.Height)
+	for x := 0; x < opts.Width; x++ {
+		img.SetRGBA(x, mid, axisColor)
+	}
+	for x, c := range columns(data, opts.Width) {
+		if len(data) == 0 {
+			break
+		}
+		for row := y(c.max, opts.Height); row <= y(c.min, opts.Height); row++ {
+			img.SetRGBA(x, row, peakColor)
+		}
+		for row := y(c.rms, opts.Height); row <= y(-c.rms, opts.Height); row++ {
+			img.SetRGBA(x, row, rmsColor)
+		}
+	}
+	drawSegments(img, opts, len(data))
+	return img
+}
+
+// WriteWaveformPNG renders data as a waveform and writes it to w as PNG.
+func WriteWaveformPNG(w io.Writer, data []float32, opts Options) error {
+	return png.Encode(w, Waveform(data, opts))
+}
+
+// WriteWaveformSVG renders data as a waveform and writes it to w as SVG.
+// Segment boundaries carry the segment text as a tooltip.
+func WriteWaveformSVG(w io.Writer, data []float32, opts Options) error {
+	opts = opts.withDefaults()
+	cols := columns(data, opts.Width)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		opts.Width, opts.Height, opts.Width, opts.Height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hex(backgroundColor))
+	mid := y(0, opts.Height)
+	fmt.Fprintf(&b, `<line x1="0" y1="%d" x2="%d" y2="%d" stroke="%s"/>`+"\n", mid, opts.Width, mid, hex(axisColor))
+	if len(data) > 0 {
+		b.WriteString(envelope(cols, opts.Height, peakColor, func(c column) (float64, float64) { return c.max, c.min }))
+		b.WriteString(envelope(cols, opts.Height, rmsColor, func(c column) (float64, float64) { return c.rms, -c.rms }))
+	}
+	for _, bd := range opts.boundaries(len(data)) {
+		text := strings.TrimSpace(opts.Segments[bd.seg].Text)
+		fmt.Fprintf(&b, `<line x1="%d" y1="0" x2="%d" y2="%d" stroke="%s"><title>%s</title></line>`+"\n",
+			bd.x, bd.x, opts.Height, hex(segmentColor), html.EscapeString(text))
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// envelope returns an SVG polygon through the upper values of cols from
+// left to right and the lower values from right to left.
+func envelope(cols []column, height int, c color.Color, bounds func(column) (float64, float64)) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<polygon fill="%s" points="`, hex(c))
+	for x, col := range cols {
+		hi, _ := bounds(col)
+		fmt.Fprintf(&b, "%d,%d ", x, y(hi, height))
+	}
+	for x := len(cols) - 1; x >= 0; x-- {
+		_, lo := bounds(cols[x])
+		fmt.Fprintf(&b, "%d,%d ", x, y(lo, height))
+	}
+	b.WriteString(`"/>` + "\n")
+	return b.String()
+}
+
+// hex formats c as an SVG color.
+func hex(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
@@ -0,0 +1,141 @@
+// Package chunk splits long audio into chunks that can be transcribed
+// independently and stitches the per-chunk transcripts back together.
+package chunk
+
+import (
+	"math"
+	"time"
+)
+
+// Default chunking parameters.
+const (
+	DefaultMaxDuration   = 5 * time.Minute
+	DefaultOverlap       = 2 * time.Second
+	DefaultSilenceWindow = 10 * time.Second
+	DefaultSampleRate    = 16000
+
+	// silenceFrame is the length of the frames whose energy is compared
+	// when searching for a split point.
+	silenceFrame = 20 * time.Millisecond
+)
+
+// Options configures Split.
+type Options struct {
+	// SampleRate is the sample rate of the audio (default 16000).
+	SampleRate int
+	// MaxDuration is the maximum length of a chunk, excluding overlap.
+	MaxDuration time.Duration
+	// Overlap is the audio shared by consecutive chunks when they are
+	// split at fixed windows. Chunks split at silence do not overlap.
+	Overlap time.Duration
+	// SplitAtSilence splits each chunk at the quietest point within
+	// SilenceWindow before MaxDuration instead of at a fixed window.
+	SplitAtSilence bool
+	// SilenceWindow is how far before MaxDuration to search for silence.
+	SilenceWindow time.Duration
+}
+
+func (o *Options) setDefaults() {
+	if o.SampleRate <= 0 {
+		o.SampleRate = DefaultSampleRate
+	}
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = DefaultMaxDuration
+	}
+	if o.Overlap < 0 {
+		o.Overlap = 0
+	}
+	if o.Overlap >= o.MaxDuration {
+		o.Overlap = o.MaxDuration / 2
+	}
+	if o.SilenceWindow <= 0 {
+		o.SilenceWindow = DefaultSilenceWindow
+	}
+	if o.SilenceWindow > o.MaxDuration/2 {
+		o.SilenceWindow = o.MaxDuration / 2
+	}
+}
+
+// Chunk is a span of audio taken from a longer buffer.
+type Chunk struct {
+	Index  int           // Position of the chunk in the sequence
+	Offset int           // Offset of the first sample in the original buffer
+	Start  time.Duration // Offset of the chunk in time
+	Data   []float32     // The samples; shares memory with the original buffer
+
+	// Overlap is the length of audio at the start of the chunk that is
+	// also at the end of the previous chunk.
+	Overlap time.Duration
+}
+
+// End returns the time of the end of the chunk in the original buffer.
+func (c Chunk) End(sampleRate int) time.Duration {
+	return c.Start + samplesToDuration(len(c.Data), sampleRate)
+}
+
+// Split divides data into chunks according to opts.
+func Split(data []float32, opts Options) []Chunk {
+	opts.setDefaults()
+	rate := opts.SampleRate
+	maxLen := durationToSamples(opts.MaxDuration, rate)
+	overlap := durationToSamples(opts.Overlap, rate)
+	if opts.SplitAtSilence {
+		overlap = 0
+	}
+
+	var chunks []Chunk
+	for start := 0; start < len(data); {
+		end := start + maxLen
+		if end >= len(data) {
+			end = len(data)
+		} else if opts.SplitAtSilence {
+			window := durationToSamples(opts.SilenceWindow, rate)
+			end = quietestPoint(data, end-window, end, durationToSamples(silenceFrame, rate))
+		}
+		c := Chunk{
+			Index:  len(chunks),
+			Offset: start,
+			Start:  samplesToDuration(start, rate),
+			Data:   data[start:end],
+		}
+		if len(chunks) > 0 {
+			prev := chunks[len(chunks)-1]
+			if prevEnd := prev.Offset + len(prev.Data); prevEnd > start {
+				c.Overlap = samplesToDuration(prevEnd-start, rate)
+			}
+		}
+		chunks = append(chunks, c)
+		if end == len(data) {
+			break
+		}
+		start = end - overlap
+	}
+	return chunks
+}
+
+// quietestPoint returns the middle of the lowest-energy frame of data
+// between lo and hi.
+func quietestPoint(data []float32, lo, hi, frame int) int {
+	if frame < 1 {
+		frame = 1
+	}
+	best, bestEnergy := hi, math.Inf(1)
+	for i := lo; i+frame <= hi; i += frame {
+		var e float64
+		for _, v := range data[i : i+frame] {
+			e += float64(v) * float64(v)
+		}
+		if e < bestEnergy {
+			best, bestEnergy = i+frame/2, e
+		}
+	}
+	return best
+}
+
+func durationToSamples(d time.Duration, rate int) int {
+	return int(d * time.Duration(rate) / time.Second)
+}
+
+func samplesToDuration(n, rate int) time.Duration {
+	return time.Duration(n) * time.Second / time.Duration(rate)
+}
@@ -0,0 +1,89 @@
+package chunk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplitFixed(t *testing.T) {
+	const rate = 100
+	data := make([]float32, 25*rate)
+	chunks := Split(data, Options{
+		SampleRate:  rate,
+		MaxDuration: 10 * time.Second,
+		Overlap:     2 * time.Second,
+	})
+
+	want := []struct {
+		offset, length int
+		overlap        time.Duration
+	}{
+		{0, 1000, 0},
+		{800, 1000, 2 * time.Second},
+		{1600, 900, 2 * time.Second},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(want))
+	}
+	for i, w := range want {
+		c := chunks[i]
+		if c.Index != i || c.Offset != w.offset || len(c.Data) != w.length || c.Overlap != w.overlap {
+			t.Errorf("chunk %d: got index %d, offset %d, length %d, overlap %v; want offset %d, length %d, overlap %v",
+				i, c.Index, c.Offset, len(c.Data), c.Overlap, w.offset, w.length, w.overlap)
+		}
+		if wantStart := time.Duration(w.offset) * time.Second / rate; c.Start != wantStart {
+			t.Errorf("chunk %d: got start %v, want %v", i, c.Start, wantStart)
+		}
+	}
+	if end := chunks[len(chunks)-1].End(rate); end != 25*time.Second {
+		t.Errorf("last chunk ends at %v, want 25s", end)
+	}
+}
+
+func TestSplitShort(t *testing.T) {
+	chunks := Split(make([]float32, 100), Options{})
+	if len(chunks) != 1 || len(chunks[0].Data) != 100 || chunks[0].Overlap != 0 {
+		t.Fatalf("got %+v, want one chunk of the whole input", chunks)
+	}
+	if chunks := Split(nil, Options{}); len(chunks) != 0 {
+		t.Fatalf("got %d chunks of no data, want none", len(chunks))
+	}
+}
+
+func TestSplitAtSilence(t *testing.T) {
+	const rate = 100
+	data := make([]float32, 25*rate)
+	for i := range data {
+		data[i] = 1
+	}
+	// Silence from 8s to 8.2s, inside the window searched before 10s.
+	for i := 800; i < 820; i++ {
+		data[i] = 0
+	}
+	chunks := Split(data, Options{
+		SampleRate:     rate,
+		MaxDuration:    10 * time.Second,
+		Overlap:        2 * time.Second,
+		SplitAtSilence: true,
+		SilenceWindow:  3 * time.Second,
+	})
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want at least 2", len(chunks))
+	}
+	if got := len(chunks[0].Data); got < 800 || got > 820 {
+		t.Errorf("first chunk has %d samples, want a split in the silence at 800-820", got)
+	}
+	var total int
+	for i, c := range chunks {
+		if c.Overlap != 0 {
+			t.Errorf("chunk %d overlaps by %v, want no overlap when splitting at silence", i, c.Overlap)
+		}
+		if c.Offset != total {
+			t.Errorf("chunk %d starts at %d, want %d", i, c.Offset, total)
+		}
+		total += len(c.Data)
+	}
+	if total != len(data) {
+		t.Errorf("chunks cover %d samples, want %d", total, len(data))
+	}
+}
@@ -0,0 +1,155 @@
+package chunk
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tmc/audioutil/transcript"
+)
+
+// maxDuplicateWords is the longest run of words that Stitch removes when
+// the same text is transcribed on both sides of a chunk boundary.
+const maxDuplicateWords = 8
+
+// Stitch joins the transcripts of consecutive chunks into one transcript.
+// results[i] holds the segments of chunks[i] with times relative to the
+// start of the chunk; a nil entry marks a chunk that failed and is skipped.
+//
+// Segment and token times are shifted to be relative to the original
+// buffer. Where chunks overlap, segments are taken from the earlier chunk
+// up to the middle of the overlap and from the later chunk after it, words
+// repeated on both sides of the boundary are removed, and segments kept
+// from the later chunk are clamped to start no earlier than the end of the
+// segments before them.
+func Stitch(chunks []Chunk, results [][]transcript.Segment) []transcript.Segment {
+	var out []transcript.Segment
+	for i, c := range chunks {
+		if i >= len(results) || results[i] == nil {
+			continue
+		}
+		boundary := c.Start + c.Overlap/2
+		var next []transcript.Segment
+		for _, s := range results[i] {
+			s = shift(s, c)
+			if c.Overlap > 0 && (s.Start+s.End)/2 < boundary {
+				continue
+			}
+			next = append(next, s)
+		}
+		if c.Overlap > 0 {
+			// drop segments of the previous chunk that belong to this one
+			for len(out) > 0 && len(next) > 0 && (out[len(out)-1].Start+out[len(out)-1].End)/2 >= boundary {
+				out = out[:len(out)-1]
+			}
+		}
+		if len(out) > 0 && len(next) > 0 {
+			next[0] = trimRepeated(out[len(out)-1], next[0])
+			if strings.TrimSpace(next[0].Text) == "" {
+				next = next[1:]
+			}
+		}
+		if len(out) > 0 {
+			end := out[len(out)-1].End
+			for k := range next {
+				next[k] = clampStart(next[k], end)
+				end = next[k].End
+			}
+		}
+		out = append(out, next...)
+	}
+	for i := range out {
+		out[i].Num = i
+	}
+	return out
+}
+
+// shift returns s with its times offset by the start of c. Tokens without
+// times of their own are left untimed.
+func shift(s transcript.Segment, c Chunk) transcript.Segment {
+	s.Start += c.Start
+	s.End += c.Start
+	tokens := make([]transcript.Token, len(s.Tokens))
+	for i, t := range s.Tokens {
+		if t.Timed() {
+			t.Start += c.Start
+			t.End += c.Start
+		}
+		tokens[i] = t
+	}
+	s.Tokens = tokens
+	return s
+}
+
+// clampStart returns s starting no earlier than t, so that segment times
+// stay monotonic where the chunks' transcripts overlap.
+func clampStart(s transcript.Segment, t time.Duration) transcript.Segment {
+	if s.Start < t {
+		s.Start = t
+	}
+	if s.End < s.Start {
+		s.End = s.Start
+	}
+	for i := range s.Tokens {
+		if !s.Tokens[i].Timed() {
+			continue
+		}
+		if s.Tokens[i].Start < t {
+			s.Tokens[i].Start = t
+		}
+		if s.Tokens[i].End < s.Tokens[i].Start {
+			s.Tokens[i].End = s.Tokens[i].Start
+		}
+	}
+	return s
+}
+
+// trimRepeated removes from the start of next the longest run of words that
+// also ends prev.
+func trimRepeated(prev, next transcript.Segment) transcript.Segment {
+	pw := strings.Fields(prev.Text)
+	nw := strings.Fields(next.Text)
+	n := maxDuplicateWords
+	if len(pw) < n {
+		n = len(pw)
+	}
+	if len(nw) < n {
+		n = len(nw)
+	}
+	for k := n; k > 0; k-- {
+		if equalWords(pw[len(pw)-k:], nw[:k]) {
+			next.Text = " " + strings.Join(nw[k:], " ")
+			next.Tokens = trimTokens(next.Tokens, k)
+			return next
+		}
+	}
+	return next
+}
+
+// trimTokens removes the tokens that make up the first k words.
+func trimTokens(tokens []transcript.Token, k int) []transcript.Token {
+	var text string
+	for i, t := range tokens {
+		if len(strings.Fields(text)) == k && strings.HasPrefix(t.Text, " ") {
+			return tokens[i:]
+		}
+		text += t.Text
+	}
+	return nil
+}
+
+func equalWords(a, b []string) bool {
+	for i := range a {
+		if normalize(a[i]) != normalize(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize lowercases w and strips punctuation.
+func normalize(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
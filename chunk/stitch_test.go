@@ -0,0 +1,143 @@
+package chunk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tmc/audioutil/transcript"
+)
+
+func seg(start, end time.Duration, text string) transcript.Segment {
+	return transcript.Segment{Start: start, End: end, Text: text}
+}
+
+func TestStitchShiftsTimes(t *testing.T) {
+	chunks := []Chunk{
+		{Index: 0, Start: 0},
+		{Index: 1, Start: 10 * time.Second},
+	}
+	results := [][]transcript.Segment{
+		{seg(0, 4*time.Second, " one"), seg(4*time.Second, 9*time.Second, " two")},
+		{seg(1*time.Second, 5*time.Second, " three")},
+	}
+	got := Stitch(chunks, results)
+	want := []transcript.Segment{
+		{Num: 0, Start: 0, End: 4 * time.Second, Text: " one"},
+		{Num: 1, Start: 4 * time.Second, End: 9 * time.Second, Text: " two"},
+		{Num: 2, Start: 11 * time.Second, End: 15 * time.Second, Text: " three"},
+	}
+	checkSegments(t, got, want)
+}
+
+func TestStitchSkipsFailedChunks(t *testing.T) {
+	chunks := []Chunk{{Index: 0}, {Index: 1, Start: 10 * time.Second}, {Index: 2, Start: 20 * time.Second}}
+	results := [][]transcript.Segment{
+		{seg(0, time.Second, " one")},
+		nil,
+		{seg(0, time.Second, " three")},
+	}
+	got := Stitch(chunks, results)
+	want := []transcript.Segment{
+		{Num: 0, Start: 0, End: time.Second, Text: " one"},
+		{Num: 1, Start: 20 * time.Second, End: 21 * time.Second, Text: " three"},
+	}
+	checkSegments(t, got, want)
+}
+
+func TestStitchOverlap(t *testing.T) {
+	// The chunks overlap from 8s to 10s, so the boundary is at 9s.
+	chunks := []Chunk{
+		{Index: 0, Start: 0},
+		{Index: 1, Start: 8 * time.Second, Overlap: 2 * time.Second},
+	}
+	results := [][]transcript.Segment{
+		{
+			seg(0, 5*time.Second, " the quick brown"),
+			seg(5*time.Second, 8800*time.Millisecond, " fox jumps"),
+			seg(8800*time.Millisecond, 10*time.Second, " over"), // after the boundary
+		},
+		{
+			seg(0, 500*time.Millisecond, " jumps"),                           // before the boundary
+			seg(500*time.Millisecond, 3*time.Second, " Jumps over the lazy"), // repeats "jumps", starts before the previous end
+			seg(3*time.Second, 5*time.Second, " dog."),
+		},
+	}
+	got := Stitch(chunks, results)
+	want := []transcript.Segment{
+		{Num: 0, Start: 0, End: 5 * time.Second, Text: " the quick brown"},
+		{Num: 1, Start: 5 * time.Second, End: 8800 * time.Millisecond, Text: " fox jumps"},
+		{Num: 2, Start: 8800 * time.Millisecond, End: 11 * time.Second, Text: " over the lazy"},
+		{Num: 3, Start: 11 * time.Second, End: 13 * time.Second, Text: " dog."},
+	}
+	checkSegments(t, got, want)
+}
+
+func TestStitchMonotonic(t *testing.T) {
+	chunks := []Chunk{
+		{Index: 0, Start: 0},
+		{Index: 1, Start: 8 * time.Second, Overlap: 2 * time.Second},
+	}
+	results := [][]transcript.Segment{
+		{seg(0, 9500*time.Millisecond, " first")},
+		{seg(1200*time.Millisecond, 1400*time.Millisecond, " second"), seg(1400*time.Millisecond, 3*time.Second, " third")},
+	}
+	got := Stitch(chunks, results)
+	for i := 1; i < len(got); i++ {
+		if got[i].Start < got[i-1].End || got[i].End < got[i].Start {
+			t.Errorf("segment %d (%v-%v) is not after segment %d (%v-%v)",
+				i, got[i].Start, got[i].End, i-1, got[i-1].Start, got[i-1].End)
+		}
+	}
+}
+
+func TestStitchUntimedTokens(t *testing.T) {
+	// Without token timestamps, whisper reports token times of -10ms.
+	untimed := func(text string) transcript.Token {
+		return transcript.Token{Text: text, Start: -10 * time.Millisecond, End: -10 * time.Millisecond}
+	}
+	chunks := []Chunk{{Index: 0}, {Index: 1, Start: 10 * time.Second}}
+	results := [][]transcript.Segment{
+		{{Start: 0, End: 2 * time.Second, Text: " one two", Tokens: []transcript.Token{untimed(" one"), untimed(" two")}}},
+		{{Start: time.Second, End: 3 * time.Second, Text: " three four", Tokens: []transcript.Token{untimed(" three"), untimed(" four")}}},
+	}
+	got := Stitch(chunks, results)
+	for _, s := range got {
+		for _, tok := range s.Tokens {
+			if tok.Timed() {
+				t.Errorf("token %q became timed: %v to %v", tok.Text, tok.Start, tok.End)
+			}
+		}
+	}
+	// Words then divides each segment's span among its tokens in
+	// proportion to their length.
+	words := transcript.Words(got)
+	split := 11*time.Second + 2*time.Second*6/11
+	want := []transcript.Word{
+		{Text: "one", Start: 0, End: time.Second},
+		{Text: "two", Start: time.Second, End: 2 * time.Second},
+		{Text: "three", Start: 11 * time.Second, End: split},
+		{Text: "four", Start: split, End: 13 * time.Second},
+	}
+	if len(words) != len(want) {
+		t.Fatalf("got %d words %+v, want %d", len(words), words, len(want))
+	}
+	for i := range want {
+		if words[i].Text != want[i].Text || words[i].Start != want[i].Start || words[i].End != want[i].End {
+			t.Errorf("word %d: got %+v, want %+v", i, words[i], want[i])
+		}
+	}
+}
+
+func checkSegments(t *testing.T, got, want []transcript.Segment) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d segments %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Num != w.Num || g.Start != w.Start || g.End != w.End || g.Text != w.Text {
+			t.Errorf("segment %d: got {%d %v %v %q}, want {%d %v %v %q}",
+				i, g.Num, g.Start, g.End, g.Text, w.Num, w.Start, w.End, w.Text)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Command audioviz renders a WAV file as a waveform or spectrogram image.
+//
+// The output format follows the extension of -o: waveforms may be written
+// as .png or .svg, spectrograms as .png. With -segments, the boundaries of
+// the segments in a transcript written by "transcribe -format json" are
+// overlaid on the image.
+//
+// Usage:
+//
+//	audioviz [flags] input.wav
+//
+// Flags:
+//
+//	-colormap string
+//	  	spectrogram colormap: gray, hot, magma or viridis (default "viridis")
+//	-height int
+//	  	image height in pixels (default 300)
+//	-kind string
+//	  	what to render: waveform, spectrogram or mel (default "waveform")
+//	-o string
+//	  	output file (default input name with .png)
+//	-segments string
+//	  	JSON transcript whose segment boundaries to overlay
+//	-width int
+//	  	image width in pixels (default 1200)
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tmc/audioutil/audioviz"
+	"github.com/tmc/audioutil/transcript"
+	"github.com/tmc/audioutil/wavutil"
+)
+
+var (
+	flagKind     = flag.String("kind", "waveform", "what to render: waveform, spectrogram or mel")
+	flagOutput   = flag.String("o", "", "output file (default input name with .png)")
+	flagSegments = flag.String("segments", "", "JSON transcript whose segment boundaries to overlay")
+	flagColormap = flag.String("colormap", "viridis", "spectrogram colormap: gray, hot, magma or viridis")
+	flagWidth    = flag.Int("width", 1200, "image width in pixels")
+	flagHeight   = flag.Int("height", 300, "image height in pixels")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: audioviz [flags] input.wav")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0)); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+func run(input string) error {
+	output := *flagOutput
+	if output == "" {
+		output = strings.TrimSuffix(input, filepath.Ext(input)) + ".png"
+	}
+	ext := strings.ToLower(filepath.Ext(output))
+	if ext != ".png" && ext != ".svg" {
+		return fmt.Errorf("unsupported output format %q", ext)
+	}
+	switch *flagKind {
+	case "waveform":
+	case "spectrogram", "mel":
+		if ext == ".svg" {
+			return fmt.Errorf("%s can only be written as png", *flagKind)
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", *flagKind)
+	}
+	cmap, err := audioviz.ParseColormap(*flagColormap)
+	if err != nil {
+		return err
+	}
+
+	data, sampleRate, err := wavutil.LoadWAV(input)
+	if err != nil {
+		return fmt.Errorf("could not load %s: %w", input, err)
+	}
+	opts := audioviz.Options{
+		Width:      *flagWidth,
+		Height:     *flagHeight,
+		SampleRate: sampleRate,
+	}
+	if *flagSegments != "" {
+		if opts.Segments, err = readSegments(*flagSegments); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	switch {
+	case *flagKind == "waveform" && ext == ".svg":
+		err = audioviz.WriteWaveformSVG(f, data, opts)
+	case *flagKind == "waveform":
+		err = audioviz.WriteWaveformPNG(f, data, opts)
+	default:
+		err = audioviz.WriteSpectrogramPNG(f, data, audioviz.SpectrogramOptions{
+			Options:  opts,
+			Mel:      *flagKind == "mel",
+			Colormap: cmap,
+		})
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// readSegments reads the segments of a JSON transcript.
+func readSegments(path string) ([]transcript.Segment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return transcript.ReadJSON(f)
+}
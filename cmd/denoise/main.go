@@ -0,0 +1,98 @@
+// Command denoise reduces stationary background noise in WAV files.
+//
+// The noise profile is estimated from the quietest parts of the input, or
+// taken from -noise, a recording of the background noise alone. The output
+// is a mono WAV file at the sample rate of the input.
+//
+// Usage:
+//
+//	denoise [flags] input.wav output.wav
+//
+// Flags:
+//
+//	-floor float
+//	  	minimum gain applied to any frequency, 0 for none (default 0.05)
+//	-frame int
+//	  	analysis frame size in samples, a power of two of at least 64 (default 512)
+//	-noise string
+//	  	WAV file containing only background noise
+//	-reduction float
+//	  	noise over-subtraction factor (default 2, or 1 with -wiener)
+//	-wiener
+//	  	use a Wiener filter instead of spectral subtraction
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/tmc/audioutil/denoise"
+	"github.com/tmc/audioutil/wavutil"
+)
+
+var (
+	flagNoise     = flag.String("noise", "", "WAV file containing only background noise")
+	flagWiener    = flag.Bool("wiener", false, "use a Wiener filter instead of spectral subtraction")
+	flagReduction = flag.Float64("reduction", 0, "noise over-subtraction factor (default 2, or 1 with -wiener)")
+	flagFloor     = flag.Float64("floor", 0.05, "minimum gain applied to any frequency, 0 for none")
+	flagFrame     = flag.Int("frame", 512, "analysis frame size in samples, a power of two of at least 64")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: denoise [flags] input.wav output.wav")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if n := *flagFrame; n < denoise.MinFrameSize || n&(n-1) != 0 {
+		fmt.Fprintf(os.Stderr, "denoise: -frame must be a power of two of at least %d, not %d\n", denoise.MinFrameSize, n)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *flagFloor < 0 || *flagFloor > 1 {
+		fmt.Fprintf(os.Stderr, "denoise: -floor must be between 0 and 1, not %v\n", *flagFloor)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), flag.Arg(1)); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+func run(input, output string) error {
+	data, sampleRate, err := wavutil.LoadWAV(input)
+	if err != nil {
+		return fmt.Errorf("could not load %s: %w", input, err)
+	}
+	opts := denoise.Options{
+		FrameSize: *flagFrame,
+		Reduction: *flagReduction,
+		Floor:     *flagFloor,
+		NoFloor:   *flagFloor == 0,
+		Wiener:    *flagWiener,
+	}
+
+	var profile *denoise.Profile
+	if *flagNoise != "" {
+		noise, noiseRate, err := wavutil.LoadWAV(*flagNoise)
+		if err != nil {
+			return fmt.Errorf("could not load %s: %w", *flagNoise, err)
+		}
+		noise = wavutil.Resample(noise, noiseRate, sampleRate)
+		if profile, err = denoise.NoiseProfile(noise, opts); err != nil {
+			return fmt.Errorf("could not profile noise: %w", err)
+		}
+	}
+
+	out, err := denoise.Reduce(data, profile, opts)
+	if err != nil {
+		return fmt.Errorf("could not reduce noise: %w", err)
+	}
+	return wavutil.SaveWAV(output, out, sampleRate)
+}
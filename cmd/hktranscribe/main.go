@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"os"
 	"runtime"
 	"time"
 
@@ -22,6 +24,8 @@ const (
 
 var (
 	defaultTimeout = 30 * time.Second
+
+	flagPreRoll = flag.Duration("pre-roll", 0, "audio from before the hotkey to include in recordings; keeps the microphone open")
 )
 
 type App struct {
@@ -30,6 +34,7 @@ type App struct {
 }
 
 func main() {
+	flag.Parse()
 	runtime.LockOSThread()
 	ctx := context.Background()
 	app, err := newApp()
@@ -46,7 +51,15 @@ func newApp() (*App, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not create whisperaudio: %w", err)
 	}
-	whisperaudio.DumpDeviceInfo()
+	if err := wa.SetPreRoll(*flagPreRoll); err != nil {
+		return nil, fmt.Errorf("could not enable pre-roll: %w", err)
+	}
+	wa.Transcriber().EnableClean(os.Stderr)
+	if ds, err := whisperaudio.Devices(); err != nil {
+		log.Printf("could not list devices: %v", err)
+	} else {
+		whisperaudio.WriteDeviceTable(os.Stderr, ds)
+	}
 	return &App{
 		listeningToggle: make(chan struct{}, 1),
 		wa:              wa,
@@ -68,6 +81,8 @@ func (app *App) runMainLoop(ctx context.Context) {
 		audioBuffer      []float32
 	)
 	fmt.Println("ready")
+	drain := time.NewTicker(100 * time.Millisecond)
+	defer drain.Stop()
 	for {
 		select {
 		case <-app.listeningToggle:
@@ -81,10 +96,12 @@ func (app *App) runMainLoop(ctx context.Context) {
 					log.Printf("error starting whisperaudio: %v", err)
 				}
 			} else {
+				fmt.Fprintln(os.Stderr)
 				fmt.Println("transcribing...")
 				if err := app.wa.Stop(); err != nil {
 					log.Printf("error stopping whisperaudio: %v", err)
 				}
+				audioBuffer = append(audioBuffer, app.wa.ReadAvailable()...)
 				t1 := time.Now()
 				text, err := app.wa.Transcribe(audioBuffer)
 				if err != nil {
@@ -101,16 +118,21 @@ func (app *App) runMainLoop(ctx context.Context) {
 		case <-ctx.Done():
 			fmt.Println("done")
 			return
-		default:
+		case <-drain.C:
+			if listening {
+				audioBuffer = append(audioBuffer, app.wa.ReadAvailable()...)
+			}
+		case ev := <-app.wa.Events():
+			log.Printf("capture problem: %+v", ev)
+		case lv := <-app.wa.Levels():
 			if !listening {
 				continue
 			}
-			buf, err := app.wa.CollectAudioData(time.Second)
-			if err != nil {
-				log.Printf("error collecting audio data: %v", err)
-				continue
+			if lv.Warning != whisperaudio.NoWarning {
+				fmt.Fprintln(os.Stderr)
+				log.Printf("warning: %v", lv.Warning)
 			}
-			audioBuffer = append(audioBuffer, buf...)
+			fmt.Fprintf(os.Stderr, "\rlevel %s %6.1f dBFS", lv.Bar(30), lv.VU)
 		}
 	}
 }
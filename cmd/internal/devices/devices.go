@@ -0,0 +1,32 @@
+// Package devices implements the devices subcommand shared by the commands
+// in this module.
+package devices
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/tmc/audioutil/whisperaudio"
+)
+
+// Run runs the devices subcommand with the given arguments, printing the
+// audio devices to stdout as a table, or as JSON with -json.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("devices", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print devices as JSON")
+	fs.Parse(args)
+	return Print(os.Stdout, *asJSON)
+}
+
+// Print writes the audio devices to w as a table or as JSON.
+func Print(w io.Writer, asJSON bool) error {
+	devices, err := whisperaudio.Devices()
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return whisperaudio.WriteDeviceJSON(w, devices)
+	}
+	return whisperaudio.WriteDeviceTable(w, devices)
+}
@@ -44,13 +44,25 @@ type App struct {
 func newApp(cfg RightHandConfig) (*App, error) {
 	fmt.Fprintln(os.Stderr, "righthand: initializing...")
 	fmt.Fprintln(os.Stderr, "righthand: using whisper model:", cfg.WhisperModel)
+	multilingual := cfg.Language != "" && cfg.Language != "en"
 	wa, err := whisperaudio.New(
 		whisperutil.WithAutoFetch(),
 		whisperutil.WithModelName(cfg.WhisperModel),
+		whisperutil.WithModelRequirements(whisperutil.ModelRequirements{Multilingual: multilingual}),
+		whisperutil.WithOnSelect(func(rec whisperutil.Recommendation) {
+			fmt.Fprintf(os.Stderr, "righthand: selected whisper model %s (real-time factor %.2f)\n", rec.ModelName, rec.RTF)
+		}),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("could not create whisperaudio: %w", err)
 	}
+	if err := setLanguage(wa.Transcriber(), cfg); err != nil {
+		return nil, err
+	}
+	if err := wa.SetPreRoll(cfg.PreRoll); err != nil {
+		return nil, fmt.Errorf("could not enable pre-roll: %w", err)
+	}
+	wa.Transcriber().EnableClean(os.Stderr)
 	cllm, err := openai.NewChat(openai.WithModel(cfg.LLMModel))
 	if err != nil {
 		return nil, fmt.Errorf("could not create chat LLM: %w", err)
@@ -63,6 +75,33 @@ func newApp(cfg RightHandConfig) (*App, error) {
 	}, nil
 }
 
+// setLanguage configures tr to transcribe in the configured language.
+func setLanguage(tr *whisperaudio.Transcriber, cfg RightHandConfig) error {
+	switch cfg.Language {
+	case "", "en":
+		return nil
+	case "auto":
+		if !tr.Model().IsMultilingual() {
+			return fmt.Errorf("language detection needs a multilingual model, not %s", cfg.WhisperModel)
+		}
+		fallback := cfg.FallbackLanguage
+		if fallback == "" {
+			fallback = "en"
+		}
+		tr.AutoLanguage = &whisperaudio.LanguageOptions{Default: fallback}
+		tr.OnLanguage = func(lang string, ranked []whisperaudio.LanguageProb) {
+			if len(ranked) > 0 {
+				fmt.Fprintf(os.Stderr, "righthand: language %s (detected %s, p=%.2f)\n", lang, ranked[0].Language, ranked[0].P)
+			}
+		}
+		return nil
+	}
+	tr.Configure = func(mctx whisper.Context) error {
+		return mctx.SetLanguage(cfg.Language)
+	}
+	return nil
+}
+
 // run runs the app.
 func (app *App) run(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
@@ -80,6 +119,8 @@ func (app *App) runMainLoop(ctx context.Context) {
 		audioBuffer      []float32
 	)
 	fmt.Println("righthand: ready")
+	drain := time.NewTicker(100 * time.Millisecond)
+	defer drain.Stop()
 	for {
 		select {
 		case <-app.listeningToggle:
@@ -93,10 +134,12 @@ func (app *App) runMainLoop(ctx context.Context) {
 					log.Printf("error starting whisperaudio: %v", err)
 				}
 			} else {
+				fmt.Fprintln(os.Stderr)
 				fmt.Println("transcribing...")
 				if err := app.wa.Stop(); err != nil {
 					log.Printf("error stopping whisperaudio: %v", err)
 				}
+				audioBuffer = append(audioBuffer, app.wa.ReadAvailable()...)
 				if app.cfg.DumpWAVFile {
 					go wavutil.SaveWAV("output.wav", audioBuffer[:], whisper.SampleRate)
 				}
@@ -118,16 +161,21 @@ func (app *App) runMainLoop(ctx context.Context) {
 		case <-ctx.Done():
 			fmt.Println("done")
 			return
-		default:
+		case <-drain.C:
+			if listening {
+				audioBuffer = append(audioBuffer, app.wa.ReadAvailable()...)
+			}
+		case ev := <-app.wa.Events():
+			log.Printf("capture problem: %+v", ev)
+		case lv := <-app.wa.Levels():
 			if !listening {
 				continue
 			}
-			buf, err := app.wa.CollectAudioData(time.Second)
-			if err != nil {
-				log.Printf("error collecting audio data: %v", err)
-				continue
+			if lv.Warning != whisperaudio.NoWarning {
+				fmt.Fprintln(os.Stderr)
+				log.Printf("warning: %v", lv.Warning)
 			}
-			audioBuffer = append(audioBuffer, buf...)
+			fmt.Fprintf(os.Stderr, "\rlevel %s %6.1f dBFS", lv.Bar(30), lv.VU)
 
 		}
 	}
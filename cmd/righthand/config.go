@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/goccy/go-yaml"
 )
@@ -10,6 +11,7 @@ import (
 var defaultConfig = RightHandConfig{
 	LLMModel:     "gpt-4",
 	WhisperModel: "base.en",
+	Language:     "en",
 	Programs: []ProgramFewShotExamples{
 		{
 			Program: "iTerm2",
@@ -85,9 +87,22 @@ func saveYaml(path string, v interface{}) error {
 // RightHandConfig is the configuration file for RightHand.
 type RightHandConfig struct {
 	LLMModel     string                   `json:"llm_model"`
-	WhisperModel string                   `json:"whisper_model"`
+	WhisperModel string                   `json:"whisper_model"` // A model name, or "auto" to select one for this machine
 	Programs     []ProgramFewShotExamples `json:"programs"`
 
+	// Language is the spoken language, e.g. "en", or "auto" to detect it
+	// for each utterance. Languages other than English select a
+	// multilingual model when WhisperModel is "auto".
+	Language string `json:"language"`
+	// FallbackLanguage is used when Language is "auto" and no language is
+	// detected with confidence. It defaults to "en".
+	FallbackLanguage string `json:"fallback_language"`
+
+	// PreRoll, if set, keeps the microphone open between recordings so
+	// that each one starts with that much earlier audio. See
+	// whisperaudio.WhisperAudio.SetPreRoll.
+	PreRoll time.Duration `json:"pre_roll"`
+
 	DumpWAVFile bool
 }
 
@@ -8,6 +8,7 @@ import (
 	"github.com/go-audio/transforms"
 	"github.com/go-audio/wav"
 	"github.com/gordonklaus/portaudio"
+	"github.com/tmc/audioutil/cmd/internal/devices"
 )
 
 const (
@@ -19,34 +20,23 @@ const (
 	bitDepth   = 24
 )
 
-func printAudioDevices() error {
-	devices, err := portaudio.Devices()
-	if err != nil {
-		return fmt.Errorf("Error: Could not get devices - %v", err)
-	}
-
-	for i, device := range devices {
-		fmt.Printf("Device %d: %s\n", i, device.Name)
-		fmt.Printf("  MaxInputChannels: %d\n", device.MaxInputChannels)
-		fmt.Printf("  MaxOutputChannels: %d\n", device.MaxOutputChannels)
-		fmt.Printf("  DefaultLowInputLatency: %.2f ms\n", device.DefaultLowInputLatency.Seconds()*1000)
-		fmt.Printf("  DefaultLowOutputLatency: %.2f ms\n", device.DefaultLowOutputLatency.Seconds()*1000)
-		fmt.Printf("  DefaultHighInputLatency: %.2f ms\n", device.DefaultHighInputLatency.Seconds()*1000)
-		fmt.Printf("  DefaultHighOutputLatency: %.2f ms\n", device.DefaultHighOutputLatency.Seconds()*1000)
-		fmt.Printf("  DefaultSampleRate: %.2f Hz\n", device.DefaultSampleRate)
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "devices" {
+		if err := devices.Run(os.Args[2:]); err != nil {
+			fmt.Printf("Error: Could not list devices - %v", err)
+		}
+		return
 	}
 
-	return nil
-}
-
-func main() {
 	err := portaudio.Initialize()
 	if err != nil {
 		fmt.Printf("Error: Could not initialize portaudio - %v", err)
 		return
 	}
 	defer portaudio.Terminate()
-	printAudioDevices()
+	if err := devices.Print(os.Stdout, false); err != nil {
+		fmt.Printf("Error: Could not list devices - %v\n", err)
+	}
 
 	in := make([]float32, bufferSize*channels)
 	stream, err := portaudio.OpenDefaultStream(channels, 0, sampleRate, bufferSize, in)
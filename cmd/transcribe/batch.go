@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmc/audioutil/transcript"
+	"github.com/tmc/audioutil/whisperaudio"
+	"github.com/tmc/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+// batchResult is the outcome of transcribing one file in batch mode.
+type batchResult struct {
+	path    string
+	skipped bool
+	err     error
+	audio   time.Duration // length of the audio
+	elapsed time.Duration // time spent on the file
+}
+
+// runBatch transcribes the audio files under dir with the given number of
+// workers, writing a sidecar file in format next to each input. Files whose
+// sidecar already exists are skipped unless overwrite is set.
+//
+// The model decodes one file at a time, so extra workers only overlap
+// loading, filtering and writing files with transcription.
+func runBatch(tr *whisperaudio.Transcriber, dir string, format transcript.Format, overwrite bool, workers int) error {
+	paths, err := findAudioFiles(dir)
+	if err != nil {
+		return err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	start := time.Now()
+	jobs := make(chan string)
+	results := make(chan batchResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				results <- transcribeToSidecar(tr, path, format, overwrite)
+			}
+		}()
+	}
+	go func() {
+		for _, path := range paths {
+			jobs <- path
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		done, skipped int
+		audio         time.Duration
+		failures      []batchResult
+	)
+	for r := range results {
+		switch {
+		case r.err != nil:
+			fmt.Fprintf(os.Stderr, "FAIL %s: %v\n", r.path, r.err)
+			failures = append(failures, r)
+		case r.skipped:
+			skipped++
+		default:
+			fmt.Fprintf(os.Stderr, "ok   %s (%v in %v)\n", r.path, r.audio.Round(time.Second), r.elapsed.Round(time.Millisecond))
+			done++
+			audio += r.audio
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "\ntranscribed %d, skipped %d, failed %d of %d files (%v of audio in %v)\n",
+		done, skipped, len(failures), len(paths), audio.Round(time.Second), time.Since(start).Round(time.Second))
+	if len(failures) > 0 {
+		fmt.Fprintln(os.Stderr, "failures:")
+		for _, r := range failures {
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", r.path, r.err)
+		}
+		return fmt.Errorf("%d files failed", len(failures))
+	}
+	return nil
+}
+
+// findAudioFiles returns the audio files under dir in lexical order.
+func findAudioFiles(dir string) ([]string, error) {
+	exts := map[string]bool{".wav": true}
+	if *flagRawEncoding != "" {
+		exts = map[string]bool{".raw": true, ".pcm": true}
+	}
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && exts[strings.ToLower(filepath.Ext(path))] {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// sidecarPath returns the path of the transcript written for path.
+func sidecarPath(path string, format transcript.Format) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + format.Ext()
+}
+
+// transcribeToSidecar transcribes path and writes the result next to it.
+func transcribeToSidecar(tr *whisperaudio.Transcriber, path string, format transcript.Format, overwrite bool) batchResult {
+	r := batchResult{path: path}
+	out := sidecarPath(path, format)
+	if _, err := os.Stat(out); err == nil && !overwrite {
+		r.skipped = true
+		return r
+	}
+
+	t0 := time.Now()
+	channels, err := loadChannels(path)
+	if err != nil {
+		r.err = fmt.Errorf("could not load audio: %w", err)
+		return r
+	}
+	data, err := mixAudio(channels)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.audio = time.Duration(len(data)) * time.Second / whisper.SampleRate
+	segments, err := tr.Transcribe(data)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	segments = labelSpeakers(segments, channels)
+
+	// Write to a temporary file first so that an interrupted run does not
+	// leave a partial transcript that would be skipped next time.
+	tmp := out + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	err = transcript.Write(f, format, segments)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, out)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		r.err = fmt.Errorf("could not write %s: %w", out, err)
+	}
+	r.elapsed = time.Since(t0)
+	return r
+}
@@ -1,22 +1,114 @@
 // Command transcribe records audio from the microphone and transcribes it.
 //
+// With -input, it transcribes existing recordings instead. The input may be
+// a file path, a glob pattern, or "-" for standard input, and additional
+// inputs may be given as arguments. WAV files are decoded automatically;
+// headerless PCM is read when -raw-encoding is set. Audio is resampled to
+// the rate whisper expects.
+//
+// With -clean, segments that are likely hallucinations, such as
+// "[BLANK_AUDIO]" or phrases repeated in a loop, are dropped and a report
+// of what was removed is printed to standard error.
+//
+// With -filter, audio is preprocessed by a chain of filters before
+// transcription, e.g. -filter dc,highpass=100,agc. See dsp.Parse for the
+// available filters. With -denoise, stationary background noise is
+// reduced first, using a noise profile estimated from the whole recording.
+//
+// With -words, the output is a timeline of words instead of segments in
+// -format: with -format tsv, tab-separated start and end times in
+// milliseconds, probability and word; with -format json, a JSON array; and
+// otherwise one segment per word. With -align, the words of the given text
+// file, a known transcript of the audio, are matched to the recognized
+// words by spelling and written the same way. This is not forced alignment:
+// the times come from recognition, so words that were missed or misheard
+// get times interpolated from their neighbors. See
+// transcript.AlignToRecognition.
+//
+// With -speakers, segments are labeled with one of that many speakers.
+// Stereo WAV input is split by the loudest channel, and other input by
+// clustering the segments' audio. See package diarize.
+//
+// With -batch, it walks a directory and transcribes every audio file in it
+// with one loaded model. The model transcribes one file at a time; -workers
+// only lets loading, filtering and writing other files overlap with it. Each
+// transcript is written next to its input with the extension of -format,
+// and inputs that already have a transcript are skipped unless -overwrite
+// is set. A summary of the run, including failures, is printed at the end.
+//
+// "transcribe devices [-json]" lists the audio devices instead.
+//
 // Usage of transcribe:
 //
+//	-align string
+//	  	text file with a known transcript to align to the recognized words
+//	-batch string
+//	  	directory of audio files to transcribe to sidecar files
+//	-clean
+//	  	drop segments that are likely hallucinations
+//	-denoise
+//	  	reduce stationary background noise before transcription
 //	-duration duration
 //	  	duration of audio to transcribe (default 5s)
+//	-filter string
+//	  	comma-separated preprocessing filters, e.g. "dc,highpass,agc"
+//	-format string
+//	  	output format: txt, srt, vtt, json, tsv or lrc (default "txt")
+//	-input string
+//	  	file, glob or "-" for stdin to transcribe instead of recording
+//	-overwrite
+//	  	in batch mode, transcribe files that already have a transcript
+//	-raw-channels int
+//	  	channel count of raw PCM input (default 1)
+//	-raw-encoding string
+//	  	encoding of raw PCM input: u8, s16le, s24le, s32le or f32le
+//	-raw-rate int
+//	  	sample rate of raw PCM input (default 16000)
+//	-speakers int
+//	  	label segments with this many speakers (0 disables)
+//	-words
+//	  	output a timeline of words instead of segments
+//	-workers int
+//	  	number of files loaded and written concurrently in batch mode (default 1)
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/tmc/audioutil/cmd/internal/devices"
+	"github.com/tmc/audioutil/denoise"
+	"github.com/tmc/audioutil/diarize"
+	"github.com/tmc/audioutil/dsp"
+	"github.com/tmc/audioutil/transcript"
+	"github.com/tmc/audioutil/wavutil"
 	"github.com/tmc/audioutil/whisperaudio"
+	"github.com/tmc/whisper.cpp/bindings/go/pkg/whisper"
 )
 
 var (
-	flagDuration = flag.Duration("duration", 5*time.Second, "duration of audio to transcribe")
+	flagDuration    = flag.Duration("duration", 5*time.Second, "duration of audio to transcribe")
+	flagClean       = flag.Bool("clean", false, "drop segments that are likely hallucinations")
+	flagDenoise     = flag.Bool("denoise", false, "reduce stationary background noise before transcription")
+	flagFilter      = flag.String("filter", "", `comma-separated preprocessing filters, e.g. "dc,highpass,agc"`)
+	flagFormat      = flag.String("format", "txt", "output format: txt, srt, vtt, json, tsv or lrc")
+	flagInput       = flag.String("input", "", `file, glob or "-" for stdin to transcribe instead of recording`)
+	flagRawEncoding = flag.String("raw-encoding", "", "encoding of raw PCM input: u8, s16le, s24le, s32le or f32le")
+	flagRawRate     = flag.Int("raw-rate", whisper.SampleRate, "sample rate of raw PCM input")
+	flagRawChannels = flag.Int("raw-channels", 1, "channel count of raw PCM input")
+	flagBatch       = flag.String("batch", "", "directory of audio files to transcribe to sidecar files")
+	flagWorkers     = flag.Int("workers", 1, "number of files loaded and written concurrently in batch mode")
+	flagOverwrite   = flag.Bool("overwrite", false, "in batch mode, transcribe files that already have a transcript")
+	flagWords       = flag.Bool("words", false, "output a timeline of words instead of segments")
+	flagAlign       = flag.String("align", "", "text file with a known transcript to align to the recognized words")
+	flagSpeakers    = flag.Int("speakers", 0, "label segments with this many speakers (0 disables)")
 )
 
 func main() {
@@ -28,13 +120,75 @@ func main() {
 }
 
 func run() error {
-	duration := *flagDuration
+	if flag.Arg(0) == "devices" {
+		return devices.Run(flag.Args()[1:])
+	}
+	format, err := transcript.ParseFormat(*flagFormat)
+	if err != nil {
+		return err
+	}
+	inputs, err := expandInputs(*flagInput, flag.Args())
+	if err != nil {
+		return err
+	}
+	if *flagRawRate <= 0 {
+		return fmt.Errorf("bad -raw-rate %d: must be positive", *flagRawRate)
+	}
+	if *flagRawChannels <= 0 {
+		return fmt.Errorf("bad -raw-channels %d: must be positive", *flagRawChannels)
+	}
+	if _, err := dsp.Parse(*flagFilter, whisper.SampleRate); err != nil {
+		return fmt.Errorf("bad -filter: %w", err)
+	}
+	newFilter := func() dsp.Filter {
+		chain, _ := dsp.Parse(*flagFilter, whisper.SampleRate)
+		return chain
+	}
+	filtering := *flagFilter != ""
+	var alignText string
+	if *flagAlign != "" {
+		b, err := os.ReadFile(*flagAlign)
+		if err != nil {
+			return fmt.Errorf("could not read transcript to align: %w", err)
+		}
+		alignText = string(b)
+	}
+	if *flagBatch != "" && (*flagWords || *flagAlign != "") {
+		return fmt.Errorf("-words and -align cannot be used with -batch")
+	}
+
+	// Files are transcribed without opening the microphone.
+	if *flagBatch != "" || len(inputs) > 0 {
+		tr, err := whisperaudio.NewTranscriber()
+		if err != nil {
+			return fmt.Errorf("could not initialize transcriber: %w", err)
+		}
+		defer tr.Close()
+		if filtering {
+			tr.NewFilter = newFilter
+		}
+		if *flagClean {
+			tr.EnableClean(os.Stderr)
+		}
+		if *flagBatch != "" {
+			return runBatch(tr, *flagBatch, format, *flagOverwrite, *flagWorkers)
+		}
+		return transcribeFiles(tr, inputs, format, alignText)
+	}
+
 	wa, err := whisperaudio.New()
 	if err != nil {
 		return fmt.Errorf("could not initialize whisperaudio: %w", err)
 	}
-	defer wa.Stop()
+	defer wa.Close()
+	if filtering {
+		wa.SetFilter(newFilter())
+	}
+	if *flagClean {
+		wa.Transcriber().EnableClean(os.Stderr)
+	}
 
+	duration := *flagDuration
 	if err = wa.Start(); err != nil {
 		return fmt.Errorf("could not start whisperaudio: %w", err)
 	}
@@ -44,10 +198,158 @@ func run() error {
 		return fmt.Errorf("could not collect audio data: %w", err)
 	}
 
-	text, err := wa.Transcribe(data)
-	if err != nil {
+	if err := transcribe(wa.Transcriber(), [][]float32{data}, format, alignText); err != nil {
 		return fmt.Errorf("could not transcribe audio data: %w", err)
 	}
-	fmt.Println(text)
 	return nil
 }
+
+// transcribe transcribes the audio channels, mixed to mono, and writes the
+// result to stdout in format: the words of alignText aligned to the
+// recognized words if it is set, the recognized words with -words, or the
+// segments.
+func transcribe(tr *whisperaudio.Transcriber, channels [][]float32, format transcript.Format, alignText string) error {
+	data, err := mixAudio(channels)
+	if err != nil {
+		return err
+	}
+	var words []transcript.Word
+	switch {
+	case alignText != "":
+		words, err = tr.AlignToRecognition(data, alignText)
+	case *flagWords:
+		words, err = tr.TranscribeWords(data)
+	default:
+		segments, err := tr.Transcribe(data)
+		if err != nil {
+			return err
+		}
+		return transcript.Write(os.Stdout, format, labelSpeakers(segments, channels))
+	}
+	if err != nil {
+		return err
+	}
+	return transcript.WriteWords(os.Stdout, format, words)
+}
+
+// mixAudio mixes the channels to mono and, with -denoise, reduces their
+// stationary noise using a noise profile estimated from the whole
+// recording. Recordings too short to profile are left as they are.
+func mixAudio(channels [][]float32) ([]float32, error) {
+	data := wavutil.Mix(channels)
+	if !*flagDenoise {
+		return data, nil
+	}
+	out, err := denoise.Reduce(data, nil, denoise.Options{})
+	if errors.Is(err, denoise.ErrNoAudio) {
+		return data, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not reduce noise: %w", err)
+	}
+	return out, nil
+}
+
+// labelSpeakers labels the segments with speakers if -speakers is set.
+func labelSpeakers(segments []transcript.Segment, channels [][]float32) []transcript.Segment {
+	if *flagSpeakers <= 0 {
+		return segments
+	}
+	segments, _ = diarize.Label(segments, channels, diarize.Options{
+		Speakers:   *flagSpeakers,
+		SampleRate: whisper.SampleRate,
+	})
+	return segments
+}
+
+// expandInputs returns the inputs named by the -input flag and the
+// arguments, expanding glob patterns.
+func expandInputs(input string, args []string) ([]string, error) {
+	var patterns []string
+	if input != "" {
+		patterns = append(patterns, input)
+	}
+	patterns = append(patterns, args...)
+
+	var inputs []string
+	for _, p := range patterns {
+		if p == "-" {
+			inputs = append(inputs, p)
+			continue
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("bad input pattern %q: %w", p, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", p)
+		}
+		inputs = append(inputs, matches...)
+	}
+	return inputs, nil
+}
+
+// transcribeFiles transcribes each input in sequence, printing a header
+// before each transcript when there is more than one input.
+func transcribeFiles(tr *whisperaudio.Transcriber, inputs []string, format transcript.Format, alignText string) error {
+	for i, input := range inputs {
+		channels, err := loadChannels(input)
+		if err != nil {
+			return fmt.Errorf("could not load %s: %w", input, err)
+		}
+		if len(inputs) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("==> %s <==\n", input)
+		}
+		if err := transcribe(tr, channels, format, alignText); err != nil {
+			return fmt.Errorf("could not transcribe %s: %w", input, err)
+		}
+	}
+	return nil
+}
+
+// loadChannels reads the named input ("-" for stdin) and returns the
+// samples of each channel at whisper.SampleRate. Raw PCM input is mixed down
+// to a single channel.
+func loadChannels(input string) ([][]float32, error) {
+	var r io.Reader = os.Stdin
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if *flagRawEncoding != "" {
+		data, err := wavutil.ReadPCM(r, wavutil.PCMFormat{
+			SampleRate: *flagRawRate,
+			Channels:   *flagRawChannels,
+			Encoding:   *flagRawEncoding,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return [][]float32{wavutil.Resample(data, *flagRawRate, whisper.SampleRate)}, nil
+	}
+
+	// The WAV decoder needs to seek, which stdin may not support.
+	rs, ok := r.(io.ReadSeeker)
+	if !ok || input == "-" {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		rs = bytes.NewReader(b)
+	}
+	channels, sampleRate, err := wavutil.ReadWAVChannels(rs)
+	if err != nil {
+		return nil, err
+	}
+	for c, data := range channels {
+		channels[c] = wavutil.Resample(data, sampleRate, whisper.SampleRate)
+	}
+	return channels, nil
+}
@@ -0,0 +1,138 @@
+// Command whispermodel inspects and manages whisper.cpp models.
+//
+// Usage:
+//
+//	whispermodel inspect [model or file ...]
+//	whispermodel list
+//	whispermodel prune -max-size size [-keep model,...]
+//
+// The inspect subcommand validates each model file and prints its size class,
+// language support, quantization and hyperparameters. Arguments that are not
+// existing files are resolved as model names in the model cache.
+//
+// The list subcommand prints the cached models, least recently used first.
+//
+// The prune subcommand evicts least recently used models until the cache
+// is at most -max-size megabytes.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tmc/audioutil/whisperutil"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: whispermodel <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  inspect [model or file ...]  validate models and print their parameters")
+	fmt.Fprintln(os.Stderr, "  list                         list cached models")
+	fmt.Fprintln(os.Stderr, "  prune -max-size MB           evict least recently used models")
+	os.Exit(2)
+}
+
+func main() {
+	log.SetFlags(0)
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+	}
+	var err error
+	switch cmd, args := flag.Arg(0), flag.Args()[1:]; cmd {
+	case "inspect":
+		err = runInspect(args)
+	case "list":
+		err = runList(args)
+	case "prune":
+		err = runPrune(args)
+	default:
+		fmt.Fprintf(os.Stderr, "whispermodel: unknown command %q\n", cmd)
+		usage()
+	}
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// resolveModel returns arg if it names an existing file, and otherwise
+// resolves it as a model name in the model cache, without counting it as a
+// use of the model.
+func resolveModel(arg string) (string, error) {
+	if _, err := os.Stat(arg); err == nil {
+		return arg, nil
+	}
+	return whisperutil.GetModelPath(whisperutil.WithModelName(arg), whisperutil.WithoutMarkUsed())
+}
+
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("inspect: no models given")
+	}
+	failed := false
+	for _, arg := range fs.Args() {
+		path, err := resolveModel(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		mi, err := whisperutil.InspectModel(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: %v, %d tensors, %d MB\n", path, mi, mi.Tensors, mi.Size/1e6)
+	}
+	if failed {
+		return fmt.Errorf("inspect: some models are invalid")
+	}
+	return nil
+}
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.Parse(args)
+	models, err := whisperutil.CachedModels()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIZE\tLAST USED")
+	var total int64
+	for _, m := range models {
+		fmt.Fprintf(w, "%s\t%d MB\t%s\n", m.Name, m.Size/1e6, m.LastUsed.Format(time.DateTime))
+		total += m.Size
+	}
+	fmt.Fprintf(w, "total\t%d MB\t\n", total/1e6)
+	return w.Flush()
+}
+
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	maxSize := fs.Int64("max-size", 0, "maximum cache size in megabytes")
+	keep := fs.String("keep", "", "comma-separated models that are never evicted")
+	fs.Parse(args)
+	if *maxSize <= 0 {
+		return fmt.Errorf("prune: -max-size is required")
+	}
+	policy := whisperutil.CachePolicy{MaxSize: *maxSize * 1e6}
+	if *keep != "" {
+		policy.Keep = strings.Split(*keep, ",")
+	}
+	removed, err := whisperutil.Prune(policy)
+	for _, m := range removed {
+		fmt.Printf("evicted %s (%d MB)\n", m.Name, m.Size/1e6)
+	}
+	return err
+}
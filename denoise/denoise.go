@@ -0,0 +1,236 @@
+// Package denoise reduces stationary background noise, such as fans and
+// air conditioning, in speech recordings.
+//
+// Audio is analyzed with a short-time Fourier transform. A noise profile,
+// the average power of the noise in each frequency bin, is estimated from
+// the quietest frames of the recording or from a separate noise sample, and
+// each frame is attenuated by spectral subtraction or a Wiener filter before
+// the signal is resynthesized by overlap-add.
+package denoise
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/tmc/audioutil/dsp"
+)
+
+// Options configures noise reduction. Zero fields take the defaults noted
+// below.
+type Options struct {
+	// FrameSize is the analysis frame length in samples, a power of two
+	// of at least 64 (default 512, or 32ms at 16 kHz). Frames overlap by
+	// three quarters.
+	FrameSize int
+	// Reduction scales the noise estimate before it is removed. Values
+	// above 1 remove more noise at the cost of more distortion (default 2,
+	// or 1 with Wiener).
+	Reduction float64
+	// Floor is the minimum gain applied to any bin, which limits the
+	// "musical noise" artifacts of aggressive reduction (default 0.05).
+	Floor float64
+	// NoFloor disables the floor, so that bins may be removed entirely.
+	NoFloor bool
+	// Wiener selects a decision-directed Wiener filter instead of power
+	// spectral subtraction. It has fewer artifacts but removes less noise.
+	Wiener bool
+	// NoiseQuantile is the fraction of the quietest frames of a recording
+	// that EstimateProfile treats as noise (default 0.1).
+	NoiseQuantile float64
+}
+
+func (o Options) withDefaults() Options {
+	if o.FrameSize <= 0 {
+		o.FrameSize = 512
+	}
+	if o.Reduction <= 0 {
+		o.Reduction = 2
+		if o.Wiener {
+			o.Reduction = 1
+		}
+	}
+	if o.NoFloor {
+		o.Floor = 0
+	} else if o.Floor <= 0 {
+		o.Floor = 0.05
+	}
+	if o.NoiseQuantile <= 0 || o.NoiseQuantile > 1 {
+		o.NoiseQuantile = 0.1
+	}
+	return o
+}
+
+// MinFrameSize is the smallest supported FrameSize.
+const MinFrameSize = 64
+
+// validate reports whether the frame size, after defaults, is supported.
+func (o Options) validate() error {
+	if o.FrameSize < MinFrameSize || o.FrameSize&(o.FrameSize-1) != 0 {
+		return fmt.Errorf("denoise: frame size %d is not a power of two of at least %d", o.FrameSize, MinFrameSize)
+	}
+	return nil
+}
+
+func (o Options) hop() int {
+	return o.FrameSize / 4
+}
+
+// Profile is the average power of noise in each frequency bin.
+type Profile struct {
+	FrameSize int
+	Power     []float64 // FrameSize/2+1 bins
+}
+
+// ErrNoAudio is returned when there is too little audio to analyze.
+var ErrNoAudio = errors.New("denoise: not enough audio")
+
+// NoiseProfile returns the profile of noise, a recording that contains only
+// noise.
+func NoiseProfile(noise []float32, opts Options) (*Profile, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if len(noise) < opts.FrameSize {
+		return nil, ErrNoAudio
+	}
+	frames := innerFrames(dsp.STFT(noise, dsp.Hann(opts.FrameSize), opts.hop()), len(noise), opts)
+	return average(frames, opts.FrameSize), nil
+}
+
+// EstimateProfile estimates the noise profile of a recording from its
+// quietest frames, which are assumed not to contain speech.
+func EstimateProfile(data []float32, opts Options) (*Profile, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if len(data) < opts.FrameSize {
+		return nil, ErrNoAudio
+	}
+	frames := innerFrames(dsp.STFT(data, dsp.Hann(opts.FrameSize), opts.hop()), len(data), opts)
+	energy := make([]float64, len(frames))
+	for t, spec := range frames {
+		for _, c := range spec {
+			energy[t] += power(c)
+		}
+	}
+	order := make([]int, len(frames))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return energy[order[i]] < energy[order[j]] })
+
+	n := int(math.Ceil(opts.NoiseQuantile * float64(len(frames))))
+	quiet := make([][]complex128, n)
+	for i := range quiet {
+		quiet[i] = frames[order[i]]
+	}
+	return average(quiet, opts.FrameSize), nil
+}
+
+// Reduce returns data with the noise described by p removed. If p is nil,
+// the profile is estimated from data with EstimateProfile.
+func Reduce(data []float32, p *Profile, opts Options) ([]float32, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if p == nil {
+		var err error
+		if p, err = EstimateProfile(data, opts); err != nil {
+			return nil, err
+		}
+	}
+	if p.FrameSize != opts.FrameSize || len(p.Power) != opts.FrameSize/2+1 {
+		return nil, fmt.Errorf("denoise: profile frame size %d does not match %d", p.FrameSize, opts.FrameSize)
+	}
+	window := dsp.Hann(opts.FrameSize)
+	frames := dsp.STFT(data, window, opts.hop())
+	s := newSuppressor(opts, p.Power)
+	for _, spec := range frames {
+		s.apply(spec)
+	}
+	return dsp.ISTFT(frames, window, opts.hop(), len(data)), nil
+}
+
+// innerFrames returns the frames that lie entirely within a signal of n
+// samples, or all frames if there are none.
+func innerFrames(frames [][]complex128, n int, opts Options) [][]complex128 {
+	half := opts.FrameSize / 2
+	var inner [][]complex128
+	for t, f := range frames {
+		if c := t * opts.hop(); c-half >= 0 && c+half <= n {
+			inner = append(inner, f)
+		}
+	}
+	if len(inner) == 0 {
+		return frames
+	}
+	return inner
+}
+
+// average returns the mean power of frames in each bin.
+func average(frames [][]complex128, frameSize int) *Profile {
+	p := &Profile{FrameSize: frameSize, Power: make([]float64, frameSize/2+1)}
+	for _, spec := range frames {
+		for k, c := range spec {
+			p.Power[k] += power(c)
+		}
+	}
+	for k := range p.Power {
+		p.Power[k] /= float64(len(frames))
+	}
+	return p
+}
+
+func power(c complex128) float64 {
+	return real(c)*real(c) + imag(c)*imag(c)
+}
+
+// suppressor attenuates successive spectra given a noise estimate.
+type suppressor struct {
+	opts  Options
+	noise []float64
+	clean []float64 // estimated clean power of the previous frame
+}
+
+func newSuppressor(opts Options, noise []float64) *suppressor {
+	return &suppressor{
+		opts:  opts,
+		noise: noise,
+		clean: make([]float64, len(noise)),
+	}
+}
+
+// wienerSmoothing weights the previous frame in the decision-directed
+// estimate of the a priori SNR.
+const wienerSmoothing = 0.98
+
+// apply attenuates spec in place.
+func (s *suppressor) apply(spec []complex128) {
+	if s.noise == nil {
+		return
+	}
+	floor := s.opts.Floor
+	for k, c := range spec {
+		p := power(c)
+		n := s.opts.Reduction * s.noise[k]
+		var g float64
+		switch {
+		case n <= 0:
+			g = 1
+		case s.opts.Wiener:
+			post := math.Max(p/n-1, 0)
+			prior := wienerSmoothing*s.clean[k]/n + (1-wienerSmoothing)*post
+			g = prior / (1 + prior)
+		case p > 0:
+			g = math.Sqrt(math.Max(1-n/p, 0))
+		}
+		g = math.Max(g, floor)
+		s.clean[k] = g * g * p
+		spec[k] = c * complex(g, 0)
+	}
+}
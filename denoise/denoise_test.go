@@ -0,0 +1,179 @@
+package denoise
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const sampleRate = 16000
+
+// noisyTone returns 2 seconds of white noise with a 1 kHz tone in the
+// middle second, along with the tone alone.
+func noisyTone(seed int64) (noisy, clean []float32) {
+	r := rand.New(rand.NewSource(seed))
+	n := 2 * sampleRate
+	noisy, clean = make([]float32, n), make([]float32, n)
+	for i := range noisy {
+		if i >= n/4 && i < 3*n/4 {
+			clean[i] = float32(0.3 * math.Sin(2*math.Pi*1000*float64(i)/sampleRate))
+		}
+		noisy[i] = clean[i] + float32(0.03*r.NormFloat64())
+	}
+	return noisy, clean
+}
+
+// snr returns the ratio of the energy of clean to that of the difference
+// between x and clean in dB, over [lo, hi).
+func snr(x, clean []float32, lo, hi int) float64 {
+	var signal, noise float64
+	for i := lo; i < hi; i++ {
+		d := float64(x[i] - clean[i])
+		signal += float64(clean[i]) * float64(clean[i])
+		noise += d * d
+	}
+	return 10 * math.Log10(signal/noise)
+}
+
+// toneLevel returns the amplitude of the 1 kHz component of x over [lo, hi).
+func toneLevel(x []float32, lo, hi int) float64 {
+	var re, im float64
+	for i := lo; i < hi; i++ {
+		w := 2 * math.Pi * 1000 * float64(i) / sampleRate
+		re += float64(x[i]) * math.Cos(w)
+		im += float64(x[i]) * math.Sin(w)
+	}
+	return 2 * math.Hypot(re, im) / float64(hi-lo)
+}
+
+func TestReduce(t *testing.T) {
+	noisy, clean := noisyTone(1)
+	lo, hi := len(noisy)/4+1024, 3*len(noisy)/4-1024
+	for _, opts := range []Options{{}, {Wiener: true}} {
+		out, err := Reduce(noisy, nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != len(noisy) {
+			t.Fatalf("Wiener=%v: got %d samples, want %d", opts.Wiener, len(out), len(noisy))
+		}
+		before, after := snr(noisy, clean, lo, hi), snr(out, clean, lo, hi)
+		if after < before+6 {
+			t.Errorf("Wiener=%v: SNR %.1f dB -> %.1f dB, want at least 6 dB better", opts.Wiener, before, after)
+		}
+		want, got := toneLevel(clean, lo, hi), toneLevel(out, lo, hi)
+		if db := 20 * math.Log10(got/want); math.Abs(db) > 0.5 {
+			t.Errorf("Wiener=%v: tone changed by %.2f dB", opts.Wiener, db)
+		}
+	}
+}
+
+func TestReduceNoFloor(t *testing.T) {
+	noisy, _ := noisyTone(2)
+	floored, err := Reduce(noisy, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unfloored, err := Reduce(noisy, nil, Options{NoFloor: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first quarter is noise only, which the floor lets through.
+	n := len(noisy) / 4
+	if a, b := energy(unfloored[:n]), energy(floored[:n]); a >= b {
+		t.Errorf("noise energy without floor %g, want less than %g with it", a, b)
+	}
+}
+
+func TestBadFrameSize(t *testing.T) {
+	data := make([]float32, 4096)
+	for _, size := range []int{32, 500} {
+		if _, err := Reduce(data, nil, Options{FrameSize: size}); err == nil {
+			t.Errorf("Reduce with frame size %d: no error", size)
+		}
+		if _, err := NewFilter(nil, Options{FrameSize: size}); err == nil {
+			t.Errorf("NewFilter with frame size %d: no error", size)
+		}
+	}
+}
+
+func TestFilterMatchesReduce(t *testing.T) {
+	noisy, _ := noisyTone(3)
+	opts := Options{}
+	p, err := NoiseProfile(noisy[:len(noisy)/4], opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Reduce(noisy, p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewFilter(p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []float32
+	for i := 0; i < len(noisy); i += 1000 {
+		block := append([]float32(nil), noisy[i:minInt(i+1000, len(noisy))]...)
+		f.Process(block)
+		got = append(got, block...)
+	}
+	got = append(got, f.Flush()...)
+
+	delay := f.opts.FrameSize - 1
+	got = got[delay:]
+	if len(got) != len(want) {
+		t.Fatalf("got %d samples after the delay, want %d", len(got), len(want))
+	}
+	// Away from the ends, where Reduce pads the signal with zeros, both
+	// see the same frames.
+	for i := f.opts.FrameSize; i < len(want)-f.opts.FrameSize; i++ {
+		if math.Abs(float64(got[i]-want[i])) > 1e-5 {
+			t.Fatalf("sample %d: Filter %v, Reduce %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFilterSeedsFromQuietestFrame(t *testing.T) {
+	// A stream that starts with the tone, pauses briefly and resumes: the
+	// estimate must come from the pause, not the first frame.
+	noisy, _ := noisyTone(4)
+	quarter, pause := len(noisy)/4, sampleRate/10
+	var start []float32
+	start = append(start, noisy[quarter:quarter+pause]...)
+	start = append(start, noisy[:pause]...)
+	start = append(start, noisy[quarter+pause:len(noisy)/2]...)
+	f, err := NewFilter(nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Process(start)
+	var tone, noise float64
+	for k, p := range f.s.noise {
+		// Bin 32 of a 512-point frame at 16 kHz is 1 kHz.
+		if k == 32 {
+			tone = p
+		} else if k > 64 && k < 192 {
+			noise += p / 127
+		}
+	}
+	if tone > 10*noise {
+		t.Errorf("noise estimate at the tone is %.0f times that of the noise", tone/noise)
+	}
+}
+
+func energy(x []float32) float64 {
+	var e float64
+	for _, v := range x {
+		e += float64(v) * float64(v)
+	}
+	return e
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
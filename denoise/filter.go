@@ -0,0 +1,197 @@
+package denoise
+
+import "github.com/tmc/audioutil/dsp"
+
+// Filter reduces noise in a stream, one block at a time. It implements
+// dsp.Filter, so it can be part of a preprocessing chain.
+//
+// The output is delayed by FrameSize-1 samples relative to the input, and
+// the first output samples are silent. Flush returns the output still
+// buffered at the end of a stream.
+type Filter struct {
+	opts    Options
+	profile *Profile
+	window  []float64
+	s       *suppressor
+	noiseE  float64      // total power of the adaptive noise estimate
+	seed    []complex128 // quietest frame seen while seeding the estimate
+	seedE   float64      // total power of seed
+	seeded  int          // frames seen while seeding
+	flush   bool         // whether the input is padding added by Flush
+
+	in    []float32 // the most recent FrameSize input samples, circular
+	inAt  int       // index in in of the oldest sample
+	count int       // input samples received, up to FrameSize
+	fresh int       // input samples received since the last frame
+	buf   []float64 // the windowed frame
+	acc   []float64 // overlap-add accumulator
+	norm  []float64 // sum of squared windows in acc
+	out   []float32 // completed output samples
+	outAt int       // next sample of out to emit
+}
+
+// Ensure Filter implements dsp.Filter.
+var _ dsp.Filter = (*Filter)(nil)
+
+// NewFilter returns a Filter that removes the noise described by p. If p is
+// nil, the noise profile is tracked adaptively from frames that are not much
+// louder than the current estimate.
+func NewFilter(p *Profile, opts Options) (*Filter, error) {
+	opts = opts.withDefaults()
+	if p != nil && p.FrameSize != opts.FrameSize {
+		opts.FrameSize = p.FrameSize
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	f := &Filter{
+		opts:    opts,
+		profile: p,
+		window:  dsp.Hann(opts.FrameSize),
+		in:      make([]float32, opts.FrameSize),
+		buf:     make([]float64, opts.FrameSize),
+		acc:     make([]float64, opts.FrameSize),
+		norm:    make([]float64, opts.FrameSize),
+		out:     make([]float32, opts.hop()),
+	}
+	f.Reset()
+	return f, nil
+}
+
+// Reset clears the buffered audio and, for an adaptive filter, the noise
+// estimate.
+func (f *Filter) Reset() {
+	for i := range f.in {
+		f.in[i], f.acc[i], f.norm[i] = 0, 0, 0
+	}
+	f.inAt, f.fresh, f.count, f.outAt = 0, 0, 0, len(f.out)
+	var noise []float64
+	if f.profile != nil {
+		noise = append(noise, f.profile.Power...)
+	}
+	f.s = newSuppressor(f.opts, noise)
+	f.noiseE, f.seed, f.seedE, f.seeded, f.flush = 0, nil, 0, 0, false
+}
+
+// Process replaces block with denoised audio.
+func (f *Filter) Process(block []float32) {
+	hop := f.opts.hop()
+	size := f.opts.FrameSize
+	for i, x := range block {
+		f.in[f.inAt] = x
+		if f.inAt++; f.inAt == size {
+			f.inAt = 0
+		}
+		if f.count < size {
+			f.count++
+		}
+		if f.fresh++; f.fresh == hop {
+			f.fresh = 0
+			f.frame()
+		}
+		if f.outAt < len(f.out) {
+			block[i] = f.out[f.outAt]
+			f.outAt++
+		} else {
+			block[i] = 0
+		}
+	}
+}
+
+// Flush returns the last FrameSize-1 samples of output, which are still
+// buffered when the input ends, and resets f for a new stream.
+func (f *Filter) Flush() []float32 {
+	tail := make([]float32, f.opts.FrameSize-1)
+	f.flush = true
+	f.Process(tail)
+	f.Reset()
+	return tail
+}
+
+// adaptive noise tracking parameters
+const (
+	seedFrames  = 32    // frames whose quietest seeds the estimate
+	speechRatio = 2     // frames this much louder than the noise are speech
+	noiseUpdate = 0.05  // weight of a noise frame in the estimate
+	noiseDrift  = 1.001 // growth per speech frame, so the estimate can rise
+)
+
+// frame analyzes the most recent frame, adds it to the accumulator and
+// moves the completed samples to out.
+func (f *Filter) frame() {
+	size, hop := f.opts.FrameSize, f.opts.hop()
+	// The oldest sample is at inAt.
+	n := copyWindowed(f.buf, f.in[f.inAt:], f.window)
+	copyWindowed(f.buf[n:], f.in[:f.inAt], f.window[n:])
+	spec := dsp.RealFFT(f.buf)
+	if f.profile == nil && !f.flush {
+		f.track(spec)
+	}
+	f.s.apply(spec)
+	for i, v := range dsp.InverseRealFFT(spec, size) {
+		f.acc[i] += v * f.window[i]
+		f.norm[i] += f.window[i] * f.window[i]
+	}
+
+	for i := 0; i < hop; i++ {
+		f.out[i] = 0
+		if f.norm[i] > 1e-8 {
+			f.out[i] = float32(f.acc[i] / f.norm[i])
+		}
+	}
+	f.outAt = 0
+	copy(f.acc, f.acc[hop:])
+	copy(f.norm, f.norm[hop:])
+	for i := size - hop; i < size; i++ {
+		f.acc[i], f.norm[i] = 0, 0
+	}
+}
+
+// copyWindowed stores the samples of src multiplied by window in dst and
+// returns the number stored.
+func copyWindowed(dst []float64, src []float32, window []float64) int {
+	for i, v := range src {
+		dst[i] = float64(v) * window[i]
+	}
+	return len(src)
+}
+
+// track updates the adaptive noise estimate with spec. Frames that are
+// not yet full of input are ignored. The estimate is seeded with the
+// quietest of the first seedFrames frames, since the stream may start with
+// speech; until then, no noise is removed.
+func (f *Filter) track(spec []complex128) {
+	if f.count < f.opts.FrameSize {
+		return
+	}
+	var e float64
+	for _, c := range spec {
+		e += power(c)
+	}
+	switch {
+	case f.seeded < seedFrames:
+		if f.seed == nil || e < f.seedE {
+			f.seed = append(f.seed[:0], spec...)
+			f.seedE = e
+		}
+		if f.seeded++; f.seeded < seedFrames {
+			return
+		}
+		f.s.noise = make([]float64, len(spec))
+		for k, c := range f.seed {
+			f.s.noise[k] = power(c)
+		}
+		f.s.clean = make([]float64, len(spec))
+		f.noiseE = f.seedE
+	case e < speechRatio*f.noiseE:
+		for k, c := range spec {
+			f.s.noise[k] += noiseUpdate * (power(c) - f.s.noise[k])
+		}
+		f.noiseE += noiseUpdate * (e - f.noiseE)
+	default:
+		for k := range f.s.noise {
+			f.s.noise[k] *= noiseDrift
+		}
+		f.noiseE *= noiseDrift
+	}
+}
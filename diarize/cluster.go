@@ -0,0 +1,211 @@
+package diarize
+
+import (
+	"math"
+
+	"github.com/tmc/audioutil/dsp"
+	"github.com/tmc/audioutil/transcript"
+)
+
+const (
+	frameSize  = 512
+	hopSize    = 160
+	melBands   = 40
+	iterations = 20
+)
+
+// Cluster returns a copy of the segments labeled with speakers by grouping
+// them with k-means into opts.Speakers clusters. Each segment is described
+// by the mean and standard deviation of its log-mel spectrum, which mostly
+// reflect the voice and microphone rather than the words. Speakers are
+// numbered in order of appearance. Segments too short to measure take the
+// previous segment's speaker.
+func Cluster(segments []transcript.Segment, data []float32, opts Options) []transcript.Segment {
+	opts = opts.withDefaults()
+	features := segmentFeatures(segments, data, opts.SampleRate)
+	labels := kmeans(features, opts.Speakers)
+
+	out := make([]transcript.Segment, len(segments))
+	order := make(map[int]int) // cluster to speaker
+	previous := 1
+	for i, s := range segments {
+		if c := labels[i]; c >= 0 {
+			if _, ok := order[c]; !ok {
+				order[c] = len(order) + 1
+			}
+			previous = order[c]
+		}
+		s.Speaker = previous
+		out[i] = s
+	}
+	return out
+}
+
+// segmentFeatures returns the features of each segment, standardized
+// across segments, or nil for segments too short to measure. Each segment's
+// spectrum is computed from its own samples, using only frames that lie
+// wholly within it, so neither neighbouring speech nor padding is counted.
+func segmentFeatures(segments []transcript.Segment, data []float32, sampleRate int) [][]float64 {
+	window := dsp.Hann(frameSize)
+	filters := dsp.MelFilterbank(sampleRate, frameSize, melBands)
+
+	features := make([][]float64, len(segments))
+	for i, s := range segments {
+		samples := span(data, s.Start, s.End, sampleRate)
+		// STFT frames are centered on multiples of hopSize; keep those
+		// that start at or after the first sample and end by the last.
+		lo := (frameSize/2 + hopSize - 1) / hopSize
+		hi := (len(samples)-frameSize/2)/hopSize + 1
+		if len(samples) < frameSize || hi-lo < 2 {
+			continue
+		}
+		power := dsp.PowerSpectrogram(samples, window, hopSize)
+		mel := dsp.MelSpectrogram(power[lo:hi], filters)
+
+		f := make([]float64, 2*melBands)
+		for _, frame := range mel {
+			for k, v := range frame {
+				l := math.Log10(v + 1e-10)
+				f[k] += l
+				f[melBands+k] += l * l
+			}
+		}
+		n := float64(hi - lo)
+		for k := 0; k < melBands; k++ {
+			mean := f[k] / n
+			f[k] = mean
+			f[melBands+k] = math.Sqrt(math.Max(f[melBands+k]/n-mean*mean, 0))
+		}
+		features[i] = f
+	}
+	standardize(features)
+	return features
+}
+
+// standardize scales each dimension of the non-nil features to zero mean
+// and unit variance.
+func standardize(features [][]float64) {
+	var (
+		n    float64
+		mean []float64
+		sq   []float64
+	)
+	for _, f := range features {
+		if f == nil {
+			continue
+		}
+		if mean == nil {
+			mean, sq = make([]float64, len(f)), make([]float64, len(f))
+		}
+		for k, v := range f {
+			mean[k] += v
+			sq[k] += v * v
+		}
+		n++
+	}
+	for k := range mean {
+		mean[k] /= n
+		sq[k] = math.Sqrt(math.Max(sq[k]/n-mean[k]*mean[k], 0))
+	}
+	for _, f := range features {
+		for k := range f {
+			if sq[k] > 0 {
+				f[k] = (f[k] - mean[k]) / sq[k]
+			} else {
+				f[k] = 0
+			}
+		}
+	}
+}
+
+// kmeans clusters the non-nil features into at most k clusters and returns
+// the cluster of each, or -1 for nil features. The first center is the
+// first feature and each further one the feature farthest from the
+// centers so far, so the result is deterministic.
+func kmeans(features [][]float64, k int) []int {
+	labels := make([]int, len(features))
+	var points []int
+	for i, f := range features {
+		labels[i] = -1
+		if f != nil {
+			points = append(points, i)
+		}
+	}
+	if len(points) == 0 {
+		return labels
+	}
+	if k > len(points) {
+		k = len(points)
+	}
+
+	centers := [][]float64{append([]float64(nil), features[points[0]]...)}
+	for len(centers) < k {
+		far, farDist := -1, -1.0
+		for _, i := range points {
+			if d := nearestDist(features[i], centers); d > farDist {
+				far, farDist = i, d
+			}
+		}
+		centers = append(centers, append([]float64(nil), features[far]...))
+	}
+
+	for iter := 0; iter < iterations; iter++ {
+		changed := false
+		for _, i := range points {
+			c := nearest(features[i], centers)
+			if c != labels[i] {
+				labels[i], changed = c, true
+			}
+		}
+		if !changed {
+			break
+		}
+		for c := range centers {
+			var n float64
+			sum := make([]float64, len(centers[c]))
+			for _, i := range points {
+				if labels[i] != c {
+					continue
+				}
+				for d, v := range features[i] {
+					sum[d] += v
+				}
+				n++
+			}
+			if n == 0 {
+				continue
+			}
+			for d := range sum {
+				sum[d] /= n
+			}
+			centers[c] = sum
+		}
+	}
+	return labels
+}
+
+// nearest returns the index of the center nearest to f.
+func nearest(f []float64, centers [][]float64) int {
+	best, bestDist := 0, math.Inf(1)
+	for c, center := range centers {
+		if d := dist(f, center); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// nearestDist returns the distance from f to the nearest center.
+func nearestDist(f []float64, centers [][]float64) float64 {
+	return dist(f, centers[nearest(f, centers)])
+}
+
+// dist returns the squared Euclidean distance between a and b.
+func dist(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
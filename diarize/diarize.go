@@ -0,0 +1,132 @@
+// Package diarize labels the segments of a transcript with the speakers who
+// said them, for recordings of a conversation between a few people, such as
+// interviews and meetings.
+//
+// Two methods are provided:
+//
+//   - Channels attributes each segment to the loudest channel of a
+//     recording in which each speaker has their own microphone.
+//   - Cluster groups segments by the average spectrum of their audio.
+//
+// Label picks the best method for the input.
+//
+// Whisper.cpp can also predict speaker turns with tinydiarize models (e.g.
+// small.en-tdrz), but the Go bindings this module uses
+// (github.com/tmc/whisper.cpp/bindings/go v0.0.0-20230705062322-9af4a3211895)
+// have no way to enable that prediction, so turns are not used.
+package diarize
+
+import (
+	"math"
+	"time"
+
+	"github.com/tmc/audioutil/transcript"
+	"github.com/tmc/audioutil/wavutil"
+)
+
+// Method is a diarization method.
+type Method string
+
+const (
+	MethodChannels Method = "channels"
+	MethodCluster  Method = "cluster"
+)
+
+// Options configures diarization. Zero fields take the defaults noted below.
+type Options struct {
+	// Speakers is the number of speakers (default 2). Channels uses one
+	// speaker per channel instead.
+	Speakers int
+	// SampleRate is the sample rate of the audio (default 16000).
+	SampleRate int
+	// ChannelMargin is how many dB louder than the other channels a
+	// channel must be for Channels to attribute a segment to it (default
+	// 3). Segments without a clear channel keep the previous speaker.
+	ChannelMargin float64
+}
+
+func (o Options) withDefaults() Options {
+	if o.Speakers <= 0 {
+		o.Speakers = 2
+	}
+	if o.SampleRate <= 0 {
+		o.SampleRate = 16000
+	}
+	if o.ChannelMargin <= 0 {
+		o.ChannelMargin = 3
+	}
+	return o
+}
+
+// Label returns a copy of the segments labeled with speakers, along with
+// the method used: Channels if there is more than one channel, and Cluster
+// otherwise. Channels holds the audio the segments were transcribed from,
+// one slice per channel. Tinydiarize speaker turns are not used; see the
+// package documentation.
+func Label(segments []transcript.Segment, channels [][]float32, opts Options) ([]transcript.Segment, Method) {
+	if len(channels) > 1 {
+		return Channels(segments, channels, opts), MethodChannels
+	}
+	return Cluster(segments, wavutil.Mix(channels), opts), MethodCluster
+}
+
+// Channels returns a copy of the segments labeled with the channel, counted
+// from 1, that is loudest during each of them.
+func Channels(segments []transcript.Segment, channels [][]float32, opts Options) []transcript.Segment {
+	opts = opts.withDefaults()
+	out := make([]transcript.Segment, len(segments))
+	previous := 0
+	for i, s := range segments {
+		best, second := math.Inf(-1), math.Inf(-1)
+		speaker := 0
+		for c, ch := range channels {
+			db := level(span(ch, s.Start, s.End, opts.SampleRate))
+			switch {
+			case db > best:
+				best, second, speaker = db, best, c+1
+			case db > second:
+				second = db
+			}
+		}
+		if best-second < opts.ChannelMargin && previous != 0 {
+			speaker = previous
+		}
+		s.Speaker, previous = speaker, speaker
+		out[i] = s
+	}
+	return out
+}
+
+// span returns the samples of data between start and end.
+func span(data []float32, start, end time.Duration, sampleRate int) []float32 {
+	lo := sample(start, sampleRate, len(data))
+	hi := sample(end, sampleRate, len(data))
+	if hi < lo {
+		hi = lo
+	}
+	return data[lo:hi]
+}
+
+// sample returns the index of the sample at d, clamped to [0, n].
+func sample(d time.Duration, sampleRate, n int) int {
+	i := int(d.Seconds() * float64(sampleRate))
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+// level returns the RMS level of data in dB.
+func level(data []float32) float64 {
+	var sum float64
+	for _, v := range data {
+		sum += float64(v) * float64(v)
+	}
+	if len(data) == 0 {
+		return math.Inf(-1)
+	}
+	return 10 * math.Log10(sum/float64(len(data))+1e-12)
+}
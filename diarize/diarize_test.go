@@ -0,0 +1,113 @@
+package diarize
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/tmc/audioutil/transcript"
+)
+
+const sampleRate = 16000
+
+// segments returns n one-second segments.
+func segments(n int) []transcript.Segment {
+	out := make([]transcript.Segment, n)
+	for i := range out {
+		out[i] = transcript.Segment{Num: i, Start: time.Duration(i) * time.Second, End: time.Duration(i+1) * time.Second}
+	}
+	return out
+}
+
+// voice returns a second of a harmonic tone with the given fundamental,
+// a crude stand-in for a voice, with a little noise.
+func voice(r *rand.Rand, f0, amp float64) []float32 {
+	x := make([]float32, sampleRate)
+	for i := range x {
+		var v float64
+		for h := 1; h <= 4; h++ {
+			v += math.Sin(2*math.Pi*f0*float64(h)*float64(i)/sampleRate) / float64(h)
+		}
+		x[i] = float32(amp*v + 0.001*r.NormFloat64())
+	}
+	return x
+}
+
+func speakers(segments []transcript.Segment) []int {
+	out := make([]int, len(segments))
+	for i, s := range segments {
+		out[i] = s.Speaker
+	}
+	return out
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestChannels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	// Each speaker is loud on their own channel and faint on the other's.
+	// In the fourth second both are equally loud, so it keeps the previous
+	// speaker.
+	turns := []int{2, 1, 1, 0, 2}
+	left, right := make([]float32, 0, len(turns)*sampleRate), make([]float32, 0, len(turns)*sampleRate)
+	for _, sp := range turns {
+		l, rt := 0.3, 0.3
+		switch sp {
+		case 1:
+			rt = 0.02
+		case 2:
+			l = 0.02
+		}
+		left = append(left, voice(r, 150, l)...)
+		right = append(right, voice(r, 150, rt)...)
+	}
+	got, method := Label(segments(len(turns)), [][]float32{left, right}, Options{})
+	if method != MethodChannels {
+		t.Errorf("method %q, want %q", method, MethodChannels)
+	}
+	if want := []int{2, 1, 1, 1, 2}; !equalInts(speakers(got), want) {
+		t.Errorf("speakers %v, want %v", speakers(got), want)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	// Two voices of different pitch and level take turns.
+	turns := []int{1, 1, 2, 1, 2, 2, 1, 2}
+	var data []float32
+	for _, sp := range turns {
+		if sp == 1 {
+			data = append(data, voice(r, 110, 0.2)...)
+		} else {
+			data = append(data, voice(r, 260, 0.1)...)
+		}
+	}
+	segs := segments(len(turns))
+	// A segment too short to measure takes the previous speaker.
+	segs = append(segs, transcript.Segment{Num: len(segs), Start: 8 * time.Second, End: 8*time.Second + 10*time.Millisecond})
+	data = append(data, voice(r, 110, 0.2)[:sampleRate/100]...)
+
+	got, method := Label(segs, [][]float32{data}, Options{})
+	if method != MethodCluster {
+		t.Errorf("method %q, want %q", method, MethodCluster)
+	}
+	if want := append(turns, 2); !equalInts(speakers(got), want) {
+		t.Errorf("speakers %v, want %v", speakers(got), want)
+	}
+	for i, s := range got {
+		if s.Num != segs[i].Num || s.Start != segs[i].Start || s.End != segs[i].End {
+			t.Errorf("segment %d changed: got %+v, want %+v", i, s, segs[i])
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package dsp provides filters for preprocessing mono audio before
+// transcription, and spectral analysis: FFTs, window functions, the
+// short-time Fourier transform and its inverse, and mel spectrograms,
+// including the log-mel features that whisper computes from its input.
+//
+// Filters process blocks of samples in place and keep state between blocks,
+// so a stream may be filtered one block at a time. A filter must not be used
+// by more than one goroutine at a time.
+package dsp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Filter processes successive blocks of audio in place.
+type Filter interface {
+	// Process filters block in place.
+	Process(block []float32)
+	// Reset clears any state carried between blocks.
+	Reset()
+}
+
+// Chain is a Filter that applies its filters in order.
+type Chain []Filter
+
+// Process applies each filter in the chain to block.
+func (c Chain) Process(block []float32) {
+	for _, f := range c {
+		f.Process(block)
+	}
+}
+
+// Reset resets each filter in the chain.
+func (c Chain) Reset() {
+	for _, f := range c {
+		f.Reset()
+	}
+}
+
+// Apply resets f and returns a filtered copy of data, processed in blocks
+// of blockSize samples as a stream would be. If blockSize is not positive,
+// data is processed as a single block. A Normalize, alone or in a chain, is
+// given all of data as one block, so that the recording as a whole reaches
+// its target.
+func Apply(f Filter, data []float32, blockSize int) []float32 {
+	out := make([]float32, len(data))
+	copy(out, data)
+	f.Reset()
+	if blockSize <= 0 {
+		blockSize = len(out)
+	}
+	apply(f, out, blockSize)
+	return out
+}
+
+// apply filters data in place. The filters of a chain are applied one
+// after another to all of data, which, as each keeps its own state, is the
+// same as applying the chain block by block.
+func apply(f Filter, data []float32, blockSize int) {
+	switch f := f.(type) {
+	case Chain:
+		for _, g := range f {
+			apply(g, data, blockSize)
+		}
+		return
+	case *Normalize:
+		f.Process(data)
+		return
+	}
+	for i := 0; i < len(data); i += blockSize {
+		end := i + blockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		f.Process(data[i:end])
+	}
+}
+
+// Parse builds a chain from a comma-separated list of filters, each
+// optionally followed by "=" and a parameter:
+//
+//	dc                 remove DC offset
+//	highpass[=Hz]      high-pass filter (default 80)
+//	preemphasis[=coef] pre-emphasis (default 0.97)
+//	gate[=dBFS]        noise gate threshold (default -50)
+//	peak[=dBFS]        peak normalization target (default -1)
+//	rms[=dBFS]         RMS normalization target (default -20)
+//	limit[=dBFS]       peak limiter threshold (default -1)
+//	agc[=dBFS]         automatic gain control target (default -20)
+//
+// For example, "dc,highpass=100,agc".
+func Parse(spec string, sampleRate int) (Chain, error) {
+	var chain Chain
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, arg, hasArg := strings.Cut(item, "=")
+		param := func(def float64) (float64, error) {
+			if !hasArg {
+				return def, nil
+			}
+			v, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return 0, fmt.Errorf("bad parameter for filter %q: %w", name, err)
+			}
+			return v, nil
+		}
+		var (
+			f   Filter
+			v   float64
+			err error
+		)
+		switch name {
+		case "dc":
+			f = NewDCBlocker(sampleRate)
+		case "highpass":
+			if v, err = param(80); err == nil {
+				f = NewHighPass(sampleRate, v)
+			}
+		case "preemphasis":
+			if v, err = param(0.97); err == nil {
+				f = &PreEmphasis{Coef: v}
+			}
+		case "gate":
+			if v, err = param(-50); err == nil {
+				f = NewNoiseGate(sampleRate, v)
+			}
+		case "peak":
+			if v, err = param(-1); err == nil {
+				f = &Normalize{Target: v}
+			}
+		case "rms":
+			if v, err = param(-20); err == nil {
+				f = &Normalize{Target: v, RMS: true}
+			}
+		case "limit":
+			if v, err = param(-1); err == nil {
+				f = NewLimiter(sampleRate, v)
+			}
+		case "agc":
+			if v, err = param(-20); err == nil {
+				f = NewAGC(sampleRate, v)
+			}
+		default:
+			return nil, fmt.Errorf("unknown filter %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, f)
+	}
+	return chain, nil
+}
+
+// dbToAmp converts decibels relative to full scale to an amplitude.
+func dbToAmp(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// peak returns the largest absolute value in block.
+func peak(block []float32) float64 {
+	var level float64
+	for _, s := range block {
+		level = math.Max(level, math.Abs(float64(s)))
+	}
+	return level
+}
+
+// rms returns the root mean square of block.
+func rms(block []float32) float64 {
+	if len(block) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range block {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(block)))
+}
+
+// timeCoef returns the one-pole smoothing coefficient for the given time
+// constant in seconds.
+func timeCoef(sampleRate int, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return math.Exp(-1 / (seconds * float64(sampleRate)))
+}
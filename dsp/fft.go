@@ -0,0 +1,90 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// FFT returns the discrete Fourier transform of x. Any length is supported:
+// even lengths are split recursively and odd lengths are transformed
+// directly, so lengths with large odd factors are slow.
+func FFT(x []complex128) []complex128 {
+	n := len(x)
+	if n <= 1 {
+		return append([]complex128(nil), x...)
+	}
+	if n%2 != 0 {
+		return dft(x)
+	}
+	even := make([]complex128, n/2)
+	odd := make([]complex128, n/2)
+	for i := 0; i < n/2; i++ {
+		even[i] = x[2*i]
+		odd[i] = x[2*i+1]
+	}
+	e, o := FFT(even), FFT(odd)
+	out := make([]complex128, n)
+	for k := 0; k < n/2; k++ {
+		t := cmplx.Rect(1, -2*math.Pi*float64(k)/float64(n)) * o[k]
+		out[k] = e[k] + t
+		out[k+n/2] = e[k] - t
+	}
+	return out
+}
+
+// dft is the direct O(n²) transform.
+func dft(x []complex128) []complex128 {
+	n := len(x)
+	out := make([]complex128, n)
+	for k := range out {
+		var sum complex128
+		for j, v := range x {
+			sum += v * cmplx.Rect(1, -2*math.Pi*float64(k*j%n)/float64(n))
+		}
+		out[k] = sum
+	}
+	return out
+}
+
+// IFFT returns the inverse discrete Fourier transform of x.
+func IFFT(x []complex128) []complex128 {
+	n := len(x)
+	c := make([]complex128, n)
+	for i, v := range x {
+		c[i] = cmplx.Conj(v)
+	}
+	out := FFT(c)
+	for i, v := range out {
+		out[i] = cmplx.Conj(v) / complex(float64(n), 0)
+	}
+	return out
+}
+
+// RealFFT returns the first len(x)/2+1 bins of the Fourier transform of the
+// real signal x. The remaining bins are their complex conjugates.
+func RealFFT(x []float64) []complex128 {
+	c := make([]complex128, len(x))
+	for i, v := range x {
+		c[i] = complex(v, 0)
+	}
+	return FFT(c)[:len(x)/2+1]
+}
+
+// InverseRealFFT returns the real signal of length n whose RealFFT is spec.
+func InverseRealFFT(spec []complex128, n int) []float64 {
+	full := make([]complex128, n)
+	for k := 0; k < n && k < len(spec); k++ {
+		full[k] = spec[k]
+	}
+	for k := n/2 + 1; k < n; k++ {
+		if n-k < len(spec) {
+			full[k] = cmplx.Conj(spec[n-k])
+		}
+	}
+	c := IFFT(full)
+	out := make([]float64, n)
+	for i, v := range c {
+		out[i] = real(v)
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+func randomComplex(r *rand.Rand, n int) []complex128 {
+	x := make([]complex128, n)
+	for i := range x {
+		x[i] = complex(r.NormFloat64(), r.NormFloat64())
+	}
+	return x
+}
+
+func TestFFT(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 3, 8, 12, 15, 64, 400, 512} {
+		x := randomComplex(r, n)
+		got, want := FFT(x), dft(x)
+		for k := range want {
+			if cmplx.Abs(got[k]-want[k]) > 1e-9*float64(n) {
+				t.Errorf("n=%d: FFT[%d] = %v, DFT = %v", n, k, got[k], want[k])
+				break
+			}
+		}
+		back := IFFT(got)
+		for i := range x {
+			if cmplx.Abs(back[i]-x[i]) > 1e-9 {
+				t.Errorf("n=%d: IFFT(FFT(x))[%d] = %v, want %v", n, i, back[i], x[i])
+				break
+			}
+		}
+	}
+}
+
+func TestRealFFT(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, n := range []int{2, 9, 16, 400} {
+		x := make([]float64, n)
+		for i := range x {
+			x[i] = r.NormFloat64()
+		}
+		spec := RealFFT(x)
+		if len(spec) != n/2+1 {
+			t.Fatalf("n=%d: got %d bins, want %d", n, len(spec), n/2+1)
+		}
+		back := InverseRealFFT(spec, n)
+		for i := range x {
+			if math.Abs(back[i]-x[i]) > 1e-9 {
+				t.Errorf("n=%d: InverseRealFFT(RealFFT(x))[%d] = %v, want %v", n, i, back[i], x[i])
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package dsp
+
+import "math"
+
+// DCBlocker removes a constant offset with a one-pole high-pass filter at
+// about 10 Hz.
+type DCBlocker struct {
+	r      float64
+	x1, y1 float64
+}
+
+// NewDCBlocker returns a DCBlocker for audio at the given sample rate.
+func NewDCBlocker(sampleRate int) *DCBlocker {
+	return &DCBlocker{r: 1 - 2*math.Pi*10/float64(sampleRate)}
+}
+
+func (f *DCBlocker) Process(block []float32) {
+	for i, s := range block {
+		x := float64(s)
+		y := x - f.x1 + f.r*f.y1
+		f.x1, f.y1 = x, y
+		block[i] = float32(y)
+	}
+}
+
+func (f *DCBlocker) Reset() { f.x1, f.y1 = 0, 0 }
+
+// HighPass is a second-order Butterworth high-pass filter, useful for
+// removing rumble and handling noise below the speech band.
+type HighPass struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+// NewHighPass returns a HighPass with the given cutoff frequency in Hz.
+func NewHighPass(sampleRate int, cutoff float64) *HighPass {
+	w := 2 * math.Pi * cutoff / float64(sampleRate)
+	alpha := math.Sin(w) / math.Sqrt2 // Q = 1/√2
+	cos := math.Cos(w)
+	a0 := 1 + alpha
+	return &HighPass{
+		b0: (1 + cos) / 2 / a0,
+		b1: -(1 + cos) / a0,
+		b2: (1 + cos) / 2 / a0,
+		a1: -2 * cos / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
+
+func (f *HighPass) Process(block []float32) {
+	for i, s := range block {
+		x := float64(s)
+		y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+		f.x2, f.x1 = f.x1, x
+		f.y2, f.y1 = f.y1, y
+		block[i] = float32(y)
+	}
+}
+
+func (f *HighPass) Reset() { f.x1, f.x2, f.y1, f.y2 = 0, 0, 0, 0 }
+
+// PreEmphasis boosts high frequencies by subtracting a fraction of the
+// previous sample: y[n] = x[n] - Coef*x[n-1].
+type PreEmphasis struct {
+	Coef float64
+	x1   float64
+}
+
+func (f *PreEmphasis) Process(block []float32) {
+	for i, s := range block {
+		x := float64(s)
+		block[i] = float32(x - f.Coef*f.x1)
+		f.x1 = x
+	}
+}
+
+func (f *PreEmphasis) Reset() { f.x1 = 0 }
+
+// NoiseGate silences audio whose envelope falls below a threshold. The gain
+// opens quickly and closes slowly so that word onsets and tails are kept.
+type NoiseGate struct {
+	threshold float64 // amplitude
+	envAttack float64
+	envDecay  float64
+	opening   float64 // gain smoothing while opening
+	closing   float64 // gain smoothing while closing
+
+	env, gain float64
+}
+
+// NewNoiseGate returns a NoiseGate with the given threshold in dBFS.
+func NewNoiseGate(sampleRate int, threshold float64) *NoiseGate {
+	return &NoiseGate{
+		threshold: dbToAmp(threshold),
+		envAttack: timeCoef(sampleRate, 0.001),
+		envDecay:  timeCoef(sampleRate, 0.050),
+		opening:   timeCoef(sampleRate, 0.005),
+		closing:   timeCoef(sampleRate, 0.150),
+	}
+}
+
+func (f *NoiseGate) Process(block []float32) {
+	for i, s := range block {
+		a := math.Abs(float64(s))
+		c := f.envDecay
+		if a > f.env {
+			c = f.envAttack
+		}
+		f.env = a + c*(f.env-a)
+
+		target, c := 0.0, f.closing
+		if f.env >= f.threshold {
+			target, c = 1, f.opening
+		}
+		f.gain = target + c*(f.gain-target)
+		block[i] = float32(float64(s) * f.gain)
+	}
+}
+
+func (f *NoiseGate) Reset() { f.env, f.gain = 0, 0 }
+
+// Normalize scales each block so that its peak, or its RMS level if RMS is
+// set, reaches Target dBFS. Apply treats the whole of its input as one
+// block, so a recording is normalized as a whole; on a stream, each block is
+// scaled on its own. Silent blocks are left unchanged.
+type Normalize struct {
+	Target float64
+	RMS    bool
+}
+
+func (f *Normalize) Process(block []float32) {
+	var level float64
+	if f.RMS {
+		level = rms(block)
+	} else {
+		level = peak(block)
+	}
+	if level == 0 {
+		return
+	}
+	gain := float32(dbToAmp(f.Target) / level)
+	for i := range block {
+		block[i] *= gain
+	}
+}
+
+func (f *Normalize) Reset() {}
+
+// Limiter keeps the signal's peaks at or below a threshold. Its gain is
+// set per sample from a peak envelope that rises at once and decays
+// slowly, so no sample exceeds the threshold and the gain recovers
+// smoothly after a loud passage. Quieter audio is passed unchanged.
+type Limiter struct {
+	threshold float64 // amplitude
+	release   float64 // envelope smoothing while decaying
+
+	env float64
+}
+
+// NewLimiter returns a Limiter with the given threshold in dBFS.
+func NewLimiter(sampleRate int, threshold float64) *Limiter {
+	return &Limiter{
+		threshold: dbToAmp(threshold),
+		release:   timeCoef(sampleRate, 0.050),
+	}
+}
+
+func (f *Limiter) Process(block []float32) {
+	for i, s := range block {
+		a := math.Abs(float64(s))
+		if a > f.env {
+			f.env = a
+		} else {
+			f.env = a + f.release*(f.env-a)
+		}
+		if f.env > f.threshold {
+			block[i] = float32(float64(s) * f.threshold / f.env)
+		}
+	}
+}
+
+func (f *Limiter) Reset() { f.env = 0 }
+
+// AGC is a simple automatic gain control that steers the RMS level of the
+// signal towards a target. The gain drops quickly when the signal is too
+// loud and rises slowly when it is too quiet, and it is held during
+// silence so that background noise is not amplified.
+type AGC struct {
+	target  float64 // amplitude
+	maxGain float64
+	floor   float64 // levels below which the gain is held
+	attack  float64 // per second, while reducing gain
+	release float64 // per second, while increasing gain
+
+	sampleRate int
+	gain       float64
+}
+
+// NewAGC returns an AGC with the given target RMS level in dBFS. The gain
+// is limited to 30 dB.
+func NewAGC(sampleRate int, target float64) *AGC {
+	return &AGC{
+		target:     dbToAmp(target),
+		maxGain:    dbToAmp(30),
+		floor:      dbToAmp(-60),
+		attack:     1 / 0.01,
+		release:    1 / 1.0,
+		sampleRate: sampleRate,
+		gain:       1,
+	}
+}
+
+func (f *AGC) Process(block []float32) {
+	if len(block) == 0 {
+		return
+	}
+	want := f.gain
+	if level := rms(block); level > f.floor {
+		want = math.Min(f.target/level, f.maxGain)
+	}
+	rate := f.release
+	if want < f.gain {
+		rate = f.attack
+	}
+	dur := float64(len(block)) / float64(f.sampleRate)
+	next := want + (f.gain-want)*math.Exp(-rate*dur)
+
+	// Ramp the gain across the block to avoid steps.
+	step := (next - f.gain) / float64(len(block))
+	for i := range block {
+		block[i] = float32(float64(block[i]) * (f.gain + step*float64(i+1)))
+	}
+	f.gain = next
+}
+
+func (f *AGC) Reset() { f.gain = 1 }
@@ -0,0 +1,57 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+// tone returns n samples of a 440 Hz sine with the given peak amplitude at
+// 16 kHz.
+func tone(n int, amp float64) []float32 {
+	x := make([]float32, n)
+	for i := range x {
+		x[i] = float32(amp * math.Sin(2*math.Pi*440*float64(i)/16000))
+	}
+	return x
+}
+
+func TestNormalize(t *testing.T) {
+	quiet := tone(16000, dbToAmp(-30))
+	for _, tt := range []struct {
+		name   string
+		filter Filter
+		level  func([]float32) float64
+		target float64
+	}{
+		{"peak", &Normalize{Target: -1}, peak, -1},
+		{"rms", &Normalize{Target: -20, RMS: true}, rms, -20},
+		{"chain", Chain{NewDCBlocker(16000), &Normalize{Target: -3}}, peak, -3},
+	} {
+		// Apply normalizes the whole input even when it filters in blocks.
+		out := Apply(tt.filter, quiet, 1024)
+		if got := 20 * math.Log10(tt.level(out)); math.Abs(got-tt.target) > 0.01 {
+			t.Errorf("%s: level %.3f dBFS, want %.3f", tt.name, got, tt.target)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	const threshold = -6
+	// A quiet passage followed by a step to 0 dBFS.
+	in := append(tone(8000, dbToAmp(-30)), tone(8000, 1)...)
+	out := Apply(NewLimiter(16000, threshold), in, 512)
+	limit := dbToAmp(threshold) + 1e-6
+	for i, s := range out {
+		if math.Abs(float64(s)) > limit {
+			t.Fatalf("sample %d = %v exceeds the %v dBFS threshold", i, s, threshold)
+		}
+	}
+	for i := 0; i < 8000; i++ {
+		if out[i] != in[i] {
+			t.Fatalf("sample %d below the threshold changed from %v to %v", i, in[i], out[i])
+		}
+	}
+	if got := 20 * math.Log10(peak(out[8000:])); got < threshold-0.5 {
+		t.Errorf("loud passage limited to %.2f dBFS, want about %v", got, threshold)
+	}
+}
@@ -0,0 +1,154 @@
+package dsp
+
+import "math"
+
+// Parameters of whisper's audio frontend.
+const (
+	WhisperSampleRate = 16000
+	WhisperNFFT       = 400 // 25ms frames
+	WhisperHop        = 160 // 10ms hop
+	WhisperMels       = 80
+)
+
+// Constants of the Slaney mel scale, which is linear below 1 kHz and
+// logarithmic above.
+const (
+	melLinearHz = 200.0 / 3 // Hz per mel below the break
+	melBreakHz  = 1000.0
+	melBreakMel = melBreakHz / melLinearHz
+)
+
+// melLogStep is the log frequency ratio per mel above the break.
+var melLogStep = math.Log(6.4) / 27
+
+// HzToMel converts a frequency to the Slaney mel scale, as used by librosa
+// and whisper.
+func HzToMel(hz float64) float64 {
+	if hz < melBreakHz {
+		return hz / melLinearHz
+	}
+	return melBreakMel + math.Log(hz/melBreakHz)/melLogStep
+}
+
+// MelToHz is the inverse of HzToMel.
+func MelToHz(mel float64) float64 {
+	if mel < melBreakMel {
+		return mel * melLinearHz
+	}
+	return melBreakHz * math.Exp(melLogStep*(mel-melBreakMel))
+}
+
+// MelFilterbank returns nMels triangular filters over the nFFT/2+1 bins of
+// an FFT of length nFFT, spaced evenly on the Slaney mel scale from 0 Hz to
+// half the sample rate and normalized to unit area. It matches
+// librosa.filters.mel with its defaults, which whisper uses.
+func MelFilterbank(sampleRate, nFFT, nMels int) [][]float64 {
+	bins := nFFT/2 + 1
+	fftFreqs := make([]float64, bins)
+	for k := range fftFreqs {
+		fftFreqs[k] = float64(k) * float64(sampleRate) / float64(nFFT)
+	}
+	maxMel := HzToMel(float64(sampleRate) / 2)
+	melFreqs := make([]float64, nMels+2)
+	for i := range melFreqs {
+		melFreqs[i] = MelToHz(maxMel * float64(i) / float64(nMels+1))
+	}
+
+	filters := make([][]float64, nMels)
+	for m := range filters {
+		lo, center, hi := melFreqs[m], melFreqs[m+1], melFreqs[m+2]
+		norm := 2 / (hi - lo)
+		filters[m] = make([]float64, bins)
+		for k, f := range fftFreqs {
+			w := math.Min((f-lo)/(center-lo), (hi-f)/(hi-center))
+			if w > 0 {
+				filters[m][k] = w * norm
+			}
+		}
+	}
+	return filters
+}
+
+// MelSpectrogram applies filters to each frame of a power spectrogram and
+// returns the energy in each mel band, indexed [frame][mel].
+func MelSpectrogram(power [][]float64, filters [][]float64) [][]float64 {
+	out := make([][]float64, len(power))
+	for t, frame := range power {
+		out[t] = make([]float64, len(filters))
+		for m, f := range filters {
+			var sum float64
+			for k, w := range f {
+				if w != 0 && k < len(frame) {
+					sum += w * frame[k]
+				}
+			}
+			out[t][m] = sum
+		}
+	}
+	return out
+}
+
+// WhisperLogMel returns the log-mel spectrogram of 16 kHz samples as
+// computed by whisper's frontend, indexed [mel][frame] like the model input.
+//
+// Frames of WhisperNFFT samples are taken every WhisperHop samples from the
+// signal reflect-padded by half a frame at each end, weighted with a Hann
+// window, and the last frame is dropped, giving len(samples)/WhisperHop
+// frames. Their power spectra are reduced to WhisperMels mel bands, and the
+// log10 energies are clamped to 8 below the maximum and scaled by
+// (x+4)/4. Whisper itself also pads the input with 30 seconds of silence;
+// callers that need identical values at the end of the input should do the
+// same.
+func WhisperLogMel(samples []float32) [][]float32 {
+	nFrames := len(samples) / WhisperHop
+	window := Hann(WhisperNFFT)
+	filters := MelFilterbank(WhisperSampleRate, WhisperNFFT, WhisperMels)
+
+	power := make([][]float64, nFrames)
+	buf := make([]float64, WhisperNFFT)
+	for t := range power {
+		start := t*WhisperHop - WhisperNFFT/2
+		for i := range buf {
+			buf[i] = float64(samples[reflect(start+i, len(samples))]) * window[i]
+		}
+		spec := RealFFT(buf)
+		power[t] = make([]float64, len(spec))
+		for k, c := range spec {
+			power[t][k] = real(c)*real(c) + imag(c)*imag(c)
+		}
+	}
+	mel := MelSpectrogram(power, filters)
+
+	max := math.Inf(-1)
+	for _, frame := range mel {
+		for m, v := range frame {
+			frame[m] = math.Log10(math.Max(v, 1e-10))
+			max = math.Max(max, frame[m])
+		}
+	}
+	out := make([][]float32, WhisperMels)
+	for m := range out {
+		out[m] = make([]float32, nFrames)
+		for t := range mel {
+			out[m][t] = float32((math.Max(mel[t][m], max-8) + 4) / 4)
+		}
+	}
+	return out
+}
+
+// reflect maps an index outside [0, n) back into it by reflecting about the
+// first and last samples, without repeating them.
+func reflect(i, n int) int {
+	if n == 1 {
+		return 0
+	}
+	period := 2 * (n - 1)
+	i %= period
+	if i < 0 {
+		i += period
+	}
+	if i >= n {
+		i = period - i
+	}
+	return i
+}
@@ -0,0 +1,82 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+// The reference values below were computed in float64 with a port of
+// whisper's audio.py (log_mel_spectrogram, with the filterbank of
+// librosa.filters.mel(sr=16000, n_fft=400, n_mels=80)). filters[0][1]
+// also matches whisper's assets/mel_filters.npz, 0.02486259.
+
+func TestMelFilterbank(t *testing.T) {
+	filters := MelFilterbank(WhisperSampleRate, WhisperNFFT, WhisperMels)
+	if len(filters) != WhisperMels || len(filters[0]) != WhisperNFFT/2+1 {
+		t.Fatalf("got %dx%d filters, want %dx%d", len(filters), len(filters[0]), WhisperMels, WhisperNFFT/2+1)
+	}
+	tests := []struct {
+		mel, bin int
+		want     float64
+	}{
+		{0, 1, 0.024862593984176087},
+		{0, 2, 0},
+		{1, 1, 0.00199082188809807},
+		{1, 2, 0.022871772096078016},
+		{10, 10, 0.019908218880980724},
+		{10, 11, 0.004954375103195391},
+		{40, 42, 0.005411105098683184},
+		{40, 43, 0.014735565741439118},
+		{40, 44, 0.00651818969466092},
+		{79, 186, 0.00036674167978496597},
+		{79, 190, 0.0022320550527126443},
+	}
+	for _, tt := range tests {
+		if got := filters[tt.mel][tt.bin]; math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("filters[%d][%d] = %v, want %v", tt.mel, tt.bin, got, tt.want)
+		}
+	}
+}
+
+func TestHzToMel(t *testing.T) {
+	for _, hz := range []float64{0, 100, 999, 1000, 4000, 8000} {
+		if got := MelToHz(HzToMel(hz)); math.Abs(got-hz) > 1e-9 {
+			t.Errorf("MelToHz(HzToMel(%v)) = %v", hz, got)
+		}
+	}
+	if got := HzToMel(1000); got != 15 {
+		t.Errorf("HzToMel(1000) = %v, want 15", got)
+	}
+}
+
+func TestWhisperLogMel(t *testing.T) {
+	samples := make([]float32, 1600)
+	for i := range samples {
+		a := float32(0.5 * math.Sin(2*math.Pi*440*float64(i)/WhisperSampleRate))
+		b := float32(0.25 * math.Sin(2*math.Pi*2500*float64(i)/WhisperSampleRate))
+		samples[i] = a + b
+	}
+	mel := WhisperLogMel(samples)
+	if len(mel) != WhisperMels || len(mel[0]) != 10 {
+		t.Fatalf("got %dx%d log-mel spectrogram, want %dx10", len(mel), len(mel[0]), WhisperMels)
+	}
+	frames := []int{0, 4, 9}
+	tests := []struct {
+		mel  int
+		want [3]float32
+	}{
+		{0, [3]float32{0.999927, -0.561796, 0.487322}},
+		{5, [3]float32{1.060789, -0.561796, 0.519968}},
+		{12, [3]float32{1.334767, 1.293524, 1.297102}},
+		{30, [3]float32{0.373609, -0.561796, 0.009443}},
+		{50, [3]float32{1.13673, 1.206758, 1.206222}},
+		{79, [3]float32{0.22413, -0.561796, -0.365625}},
+	}
+	for _, tt := range tests {
+		for i, f := range frames {
+			if got := mel[tt.mel][f]; math.Abs(float64(got-tt.want[i])) > 1e-5 {
+				t.Errorf("mel[%d][%d] = %v, want %v", tt.mel, f, got, tt.want[i])
+			}
+		}
+	}
+}
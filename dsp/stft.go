@@ -0,0 +1,64 @@
+package dsp
+
+// STFT returns the short-time Fourier transform of data. Frames are
+// len(window) samples long, start every hop samples and are centered on
+// multiples of hop, with zeros beyond the ends of data. Each frame holds
+// len(window)/2+1 bins.
+func STFT(data []float32, window []float64, hop int) [][]complex128 {
+	size := len(window)
+	frames := make([][]complex128, len(data)/hop+1)
+	buf := make([]float64, size)
+	for t := range frames {
+		start := t*hop - size/2
+		for i := range buf {
+			buf[i] = 0
+			if j := start + i; j >= 0 && j < len(data) {
+				buf[i] = float64(data[j]) * window[i]
+			}
+		}
+		frames[t] = RealFFT(buf)
+	}
+	return frames
+}
+
+// PowerSpectrogram returns the power |X|² of each bin of each frame of the
+// STFT of data.
+func PowerSpectrogram(data []float32, window []float64, hop int) [][]float64 {
+	frames := STFT(data, window, hop)
+	out := make([][]float64, len(frames))
+	for t, spec := range frames {
+		out[t] = make([]float64, len(spec))
+		for k, c := range spec {
+			out[t][k] = real(c)*real(c) + imag(c)*imag(c)
+		}
+	}
+	return out
+}
+
+// ISTFT inverts STFT by weighted overlap-add and returns n samples. Frames
+// may have been modified; the result is then the least-squares estimate of
+// a signal with that transform. Unmodified frames give back the signal if
+// hop is at most half the window length, so that every sample lies in a
+// frame.
+func ISTFT(frames [][]complex128, window []float64, hop, n int) []float32 {
+	size := len(window)
+	sum := make([]float64, n)
+	norm := make([]float64, n)
+	for t, spec := range frames {
+		start := t*hop - size/2
+		frame := InverseRealFFT(spec, size)
+		for i, v := range frame {
+			if j := start + i; j >= 0 && j < n {
+				sum[j] += v * window[i]
+				norm[j] += window[i] * window[i]
+			}
+		}
+	}
+	out := make([]float32, n)
+	for i := range out {
+		if norm[i] > 1e-8 {
+			out[i] = float32(sum[i] / norm[i])
+		}
+	}
+	return out
+}
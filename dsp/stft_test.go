@@ -0,0 +1,54 @@
+package dsp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSTFTRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	data := make([]float32, 5000)
+	for i := range data {
+		data[i] = float32(r.NormFloat64() * 0.3)
+	}
+	tests := []struct {
+		name   string
+		window []float64
+		hop    int
+	}{
+		{"hann/4", Hann(512), 128},
+		{"hann/2", Hann(400), 200},
+		{"hamming/4", Hamming(256), 64},
+		{"rectangular/2", Rectangular(256), 128},
+	}
+	for _, tt := range tests {
+		frames := STFT(data, tt.window, tt.hop)
+		if want := len(data)/tt.hop + 1; len(frames) != want {
+			t.Errorf("%s: got %d frames, want %d", tt.name, len(frames), want)
+		}
+		out := ISTFT(frames, tt.window, tt.hop, len(data))
+		for i := range data {
+			if math.Abs(float64(out[i]-data[i])) > 1e-5 {
+				t.Errorf("%s: sample %d = %v, want %v", tt.name, i, out[i], data[i])
+				break
+			}
+		}
+	}
+}
+
+func TestPowerSpectrogram(t *testing.T) {
+	// A sinusoid centered on bin 8 has its power there.
+	const n, bin = 256, 8
+	data := make([]float32, 4096)
+	for i := range data {
+		data[i] = float32(math.Sin(2 * math.Pi * bin * float64(i) / n))
+	}
+	power := PowerSpectrogram(data, Hann(n), n/4)
+	frame := power[len(power)/2]
+	for k, p := range frame {
+		if k != bin && p > frame[bin] {
+			t.Fatalf("bin %d has more power (%v) than bin %d (%v)", k, p, bin, frame[bin])
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package dsp
+
+import "math"
+
+// The window functions below return periodic windows of length n, which
+// are the usual choice for spectral analysis: the window of length n is the
+// symmetric window of length n+1 without its last sample.
+
+// Rectangular returns a window of n ones.
+func Rectangular(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 1
+	}
+	return w
+}
+
+// Hann returns a Hann window of length n.
+func Hann(n int) []float64 {
+	return cosineWindow(n, 0.5, 0.5, 0)
+}
+
+// Hamming returns a Hamming window of length n.
+func Hamming(n int) []float64 {
+	return cosineWindow(n, 0.54, 0.46, 0)
+}
+
+// Blackman returns a Blackman window of length n.
+func Blackman(n int) []float64 {
+	return cosineWindow(n, 0.42, 0.5, 0.08)
+}
+
+// cosineWindow returns the window a0 - a1 cos(2πi/n) + a2 cos(4πi/n).
+func cosineWindow(n int, a0, a1, a2 float64) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		x := 2 * math.Pi * float64(i) / float64(n)
+		w[i] = a0 - a1*math.Cos(x) + a2*math.Cos(2*x)
+	}
+	return w
+}
@@ -10,8 +10,10 @@ require (
 	github.com/goccy/go-yaml v1.11.0
 	github.com/gordonklaus/portaudio v0.0.0-20221027163845-7c3b689db3cc
 	github.com/progrium/macdriver v0.4.1-0.20230706190053-7e5bd0a70b46
+	github.com/shirou/gopsutil v3.21.10+incompatible
 	github.com/tmc/langchaingo v0.0.0-20230701162323-81dcfa6b690d
 	github.com/tmc/whisper.cpp/bindings/go v0.0.0-20230705062322-9af4a3211895
+	golang.org/x/sys v0.9.0
 )
 
 require (
@@ -30,7 +32,6 @@ require (
 	github.com/robotn/gohook v0.31.3 // indirect
 	github.com/robotn/xgb v0.0.0-20190912153532-2cb92d044934 // indirect
 	github.com/robotn/xgbutil v0.0.0-20190912154524-c861d6f87770 // indirect
-	github.com/shirou/gopsutil v3.21.10+incompatible // indirect
 	github.com/tklauser/go-sysconf v0.3.9 // indirect
 	github.com/tklauser/numcpus v0.3.0 // indirect
 	github.com/vcaesar/gops v0.21.3 // indirect
@@ -38,6 +39,5 @@ require (
 	github.com/vcaesar/keycode v0.10.0 // indirect
 	github.com/vcaesar/tt v0.20.0 // indirect
 	golang.org/x/image v0.0.0-20211028202545-6944b10bf410 // indirect
-	golang.org/x/sys v0.9.0 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 )
@@ -0,0 +1,127 @@
+// Package ringbuf provides a lock-free single-producer, single-consumer ring
+// buffer of audio samples.
+//
+// The producer never blocks: samples that do not fit are dropped and counted
+// as an overrun, so a slow consumer cannot stall an audio callback.
+package ringbuf
+
+import "sync/atomic"
+
+// Ring is a fixed-size ring buffer of samples. One goroutine may call Write
+// while another calls Read concurrently; the counters may be read from any
+// goroutine.
+type Ring struct {
+	buf  []float32
+	mask uint64
+
+	w     atomic.Uint64 // total samples written, owned by the producer
+	r     atomic.Uint64 // total samples read, owned by the consumer
+	floor atomic.Uint64 // position of the last Reset, owned by the producer
+
+	// reading is 1 more than the consumer's position while a Read is in
+	// progress, and 0 otherwise, so that the producer does not overwrite
+	// samples being read, even ones discarded by Reset.
+	reading atomic.Uint64
+
+	overruns atomic.Uint64 // writes that dropped samples
+	dropped  atomic.Uint64 // samples dropped by writes
+}
+
+// New returns a Ring holding at least size samples. The capacity is rounded
+// up to a power of two.
+func New(size int) *Ring {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	return &Ring{buf: make([]float32, n), mask: uint64(n - 1)}
+}
+
+// Cap returns the capacity of the ring in samples.
+func (r *Ring) Cap() int {
+	return len(r.buf)
+}
+
+// Len returns the number of samples available to read.
+func (r *Ring) Len() int {
+	return int(r.w.Load() - r.readPos())
+}
+
+// readPos returns the position of the next sample to read, skipping any
+// samples discarded by Reset.
+func (r *Ring) readPos() uint64 {
+	rd, f := r.r.Load(), r.floor.Load()
+	if f > rd {
+		return f
+	}
+	return rd
+}
+
+// Write appends as many samples from p as fit and returns the number
+// written. Samples that do not fit are dropped and counted as an overrun.
+func (r *Ring) Write(p []float32) int {
+	w := r.w.Load()
+	rd := r.readPos()
+	if in := r.reading.Load(); in != 0 && in-1 < rd {
+		rd = in - 1
+	}
+	free := uint64(len(r.buf)) - (w - rd)
+	n := uint64(len(p))
+	if n > free {
+		r.overruns.Add(1)
+		r.dropped.Add(n - free)
+		n = free
+	}
+	for i := uint64(0); i < n; {
+		off := (w + i) & r.mask
+		i += uint64(copy(r.buf[off:], p[i:n]))
+	}
+	r.w.Store(w + n)
+	return int(n)
+}
+
+// Read copies up to len(p) samples into p and returns the number copied.
+func (r *Ring) Read(p []float32) int {
+	// Announce the read before choosing where to start: a Reset that the
+	// producer makes after this cannot free the samples from r onwards.
+	r.reading.Store(r.r.Load() + 1)
+	defer r.reading.Store(0)
+	rd := r.readPos()
+	avail := r.w.Load() - rd
+	n := uint64(len(p))
+	if n > avail {
+		n = avail
+	}
+	for i := uint64(0); i < n; {
+		off := (rd + i) & r.mask
+		end := off + (n - i)
+		if end > uint64(len(r.buf)) {
+			end = uint64(len(r.buf))
+		}
+		i += uint64(copy(p[i:n], r.buf[off:end]))
+	}
+	r.r.Store(rd + n)
+	return int(n)
+}
+
+// Reset discards all buffered samples. It must only be called by the
+// producer, typically before writing the start of a new stream. A Read
+// running concurrently with Reset may still return discarded samples, but
+// never a mix of discarded and overwritten ones.
+func (r *Ring) Reset() {
+	r.floor.Store(r.w.Load())
+}
+
+// Stats is a snapshot of a Ring's counters.
+type Stats struct {
+	Overruns uint64 // Writes that dropped samples because the ring was full
+	Dropped  uint64 // Samples dropped by those writes
+}
+
+// Stats returns the current counters.
+func (r *Ring) Stats() Stats {
+	return Stats{
+		Overruns: r.overruns.Load(),
+		Dropped:  r.dropped.Load(),
+	}
+}
@@ -0,0 +1,207 @@
+package ringbuf
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// seq returns n consecutive values starting at start.
+func seq(start, n int) []float32 {
+	p := make([]float32, n)
+	for i := range p {
+		p[i] = float32(start + i)
+	}
+	return p
+}
+
+func checkSeq(t *testing.T, got []float32, start int) {
+	t.Helper()
+	for i, v := range got {
+		if v != float32(start+i) {
+			t.Fatalf("sample %d = %v, want %v", i, v, start+i)
+		}
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	for size, want := range map[int]int{1: 1, 5: 8, 8: 8, 1000: 1024} {
+		if got := New(size).Cap(); got != want {
+			t.Errorf("New(%d).Cap() = %d, want %d", size, got, want)
+		}
+	}
+}
+
+func TestWraparound(t *testing.T) {
+	r := New(8)
+	next := 0
+	for round := 0; round < 10; round++ {
+		// 6 samples at a time, so writes and reads straddle the end.
+		if n := r.Write(seq(next, 6)); n != 6 {
+			t.Fatalf("round %d: wrote %d samples, want 6", round, n)
+		}
+		p := make([]float32, 6)
+		if n := r.Read(p[:4]); n != 4 {
+			t.Fatalf("round %d: read %d samples, want 4", round, n)
+		}
+		if n := r.Read(p[4:]); n != 2 {
+			t.Fatalf("round %d: read %d samples, want 2", round, n)
+		}
+		checkSeq(t, p, next)
+		next += 6
+	}
+	if s := r.Stats(); s != (Stats{}) {
+		t.Errorf("stats %+v, want none", s)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	r := New(8)
+	p := make([]float32, 4)
+	if n := r.Read(p); n != 0 {
+		t.Errorf("read %d samples from an empty ring", n)
+	}
+	r.Write(seq(0, 3))
+	if n := r.Read(p); n != 3 {
+		t.Errorf("read %d samples, want the 3 available", n)
+	}
+	if n := r.Len(); n != 0 {
+		t.Errorf("Len = %d after reading everything", n)
+	}
+}
+
+func TestFull(t *testing.T) {
+	r := New(8)
+	if n := r.Write(seq(0, 10)); n != 8 {
+		t.Errorf("wrote %d samples into a ring of 8, want 8", n)
+	}
+	if n := r.Write(seq(10, 3)); n != 0 {
+		t.Errorf("wrote %d samples into a full ring", n)
+	}
+	if n := r.Len(); n != 8 {
+		t.Errorf("Len = %d, want 8", n)
+	}
+	if got, want := r.Stats(), (Stats{Overruns: 2, Dropped: 5}); got != want {
+		t.Errorf("stats %+v, want %+v", got, want)
+	}
+	// The samples that fit are kept, and reading makes room again.
+	p := make([]float32, 8)
+	r.Read(p[:5])
+	checkSeq(t, p[:5], 0)
+	if n := r.Write(seq(100, 5)); n != 5 {
+		t.Errorf("wrote %d samples after reading 5, want 5", n)
+	}
+	r.Read(p)
+	checkSeq(t, p[:3], 5)
+	checkSeq(t, p[3:], 100)
+}
+
+func TestReset(t *testing.T) {
+	r := New(8)
+	r.Write(seq(0, 7))
+	r.Read(make([]float32, 2))
+	r.Reset()
+	if n := r.Len(); n != 0 {
+		t.Errorf("Len = %d after Reset", n)
+	}
+	if n := r.Read(make([]float32, 4)); n != 0 {
+		t.Errorf("read %d samples after Reset", n)
+	}
+	// All of the ring is free again.
+	if n := r.Write(seq(100, 8)); n != 8 {
+		t.Errorf("wrote %d samples after Reset, want 8", n)
+	}
+	p := make([]float32, 8)
+	if n := r.Read(p); n != 8 {
+		t.Fatalf("read %d samples, want 8", n)
+	}
+	checkSeq(t, p, 100)
+}
+
+func TestConcurrent(t *testing.T) {
+	const total = 1 << 20
+	r := New(1024)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rng := rand.New(rand.NewSource(1))
+		for next := 0; next < total; {
+			n := 1 + rng.Intn(300)
+			if next+n > total {
+				n = total - next
+			}
+			// Only write what fits, so that nothing is dropped.
+			if free := r.Cap() - r.Len(); n > free {
+				n = free
+			}
+			if n == 0 {
+				runtime.Gosched()
+				continue
+			}
+			next += r.Write(seq(next, n))
+		}
+	}()
+
+	rng := rand.New(rand.NewSource(2))
+	p := make([]float32, 512)
+	for next := 0; next < total; {
+		n := r.Read(p[:1+rng.Intn(len(p))])
+		if n == 0 {
+			runtime.Gosched()
+			continue
+		}
+		checkSeq(t, p[:n], next)
+		next += n
+	}
+	wg.Wait()
+	if s := r.Stats(); s != (Stats{}) {
+		t.Errorf("stats %+v, want none", s)
+	}
+}
+
+func TestResetWhileReading(t *testing.T) {
+	// The producer writes a full ring of consecutive samples and resets it,
+	// over and over, while the consumer reads whole rings. Each Read must
+	// return consecutive samples from one write, never a mix of discarded
+	// and overwritten ones; the race detector checks the rest.
+	r := New(64)
+	done := make(chan struct{})
+	read := make(chan struct{})
+	go func() {
+		defer close(read)
+		p := make([]float32, 64)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			n := r.Read(p)
+			for i := 1; i < n; i++ {
+				if p[i] != p[i-1]+1 {
+					t.Errorf("read %v after %v", p[i], p[i-1])
+					return
+				}
+			}
+		}
+	}()
+	for i := 0; i < 200000; i++ {
+		r.Write(seq(i*64, 64))
+		r.Reset()
+	}
+	close(done)
+	<-read
+
+	// Once the reader has stopped, a Reset discards everything before it.
+	r.Write(seq(0, 30))
+	r.Reset()
+	r.Write(seq(1000, 10))
+	p := make([]float32, 100)
+	n := r.Read(p)
+	if n != 10 {
+		t.Fatalf("read %d samples after the last Reset, want 10", n)
+	}
+	checkSeq(t, p[:n], 1000)
+}
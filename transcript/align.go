@@ -0,0 +1,210 @@
+package transcript
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// AlignToRecognition aligns the words of a known transcript, text, to
+// recognized words, typically from Words on the transcription of the same
+// audio, and returns the transcript's words with times. It does not look at
+// the audio: the times come from the recognizer, so transcript words that
+// were misrecognized or missed are only placed approximately.
+//
+// This is not forced alignment, which would decode the audio constrained to
+// the known text. The whisper.cpp Go bindings this module uses
+// (github.com/tmc/whisper.cpp/bindings/go v0.0.0-20230705062322-9af4a3211895)
+// can neither force tokens nor prompt the decoder with text.
+//
+// The two word sequences are matched by minimum edit distance, ignoring
+// case and punctuation, where substituting a similarly spelled word costs
+// less than substituting a different one. A transcript word matched to a
+// recognized word takes its times, and its probability if the words are
+// equal. Unmatched words get a probability of zero and share the time
+// between their neighbors in proportion to their length.
+//
+// To bound time and memory on long recordings, only alignments that stay
+// near the diagonal are considered: within a twentieth of the combined
+// length of text and recognized, and at least 100 words, either side of it.
+func AlignToRecognition(text string, recognized []Word) []Word {
+	ref := strings.Fields(text)
+	if len(ref) == 0 {
+		return nil
+	}
+	refN := make([]string, len(ref))
+	for i, w := range ref {
+		refN[i] = normalize(w)
+	}
+	hypN := make([]string, len(recognized))
+	for j, w := range recognized {
+		hypN[j] = normalize(w.Text)
+	}
+	n, m := len(ref), len(recognized)
+	band := alignBand(n, m)
+	sub := func(i, j int) float64 { return 2 * (1 - similarity(refN[i], hypN[j])) }
+
+	// Row i of the edit distance table, between refN[:i] and hypN[:j],
+	// is computed for j in [lo[i], hi[i]], around j = i*m/n. Only the
+	// previous row of costs is kept; back records each cell's move.
+	lo := make([]int, n+1)
+	hi := make([]int, n+1)
+	back := make([][]byte, n+1)
+	prev := make([]float64, m+1)
+	cur := make([]float64, m+1)
+	for i := 0; i <= n; i++ {
+		c := i * m / n
+		lo[i], hi[i] = c-band, c+band
+		if lo[i] < 0 {
+			lo[i] = 0
+		}
+		if hi[i] > m {
+			hi[i] = m
+		}
+		back[i] = make([]byte, hi[i]-lo[i]+1)
+		for j := lo[i]; j <= hi[i]; j++ {
+			if i == 0 {
+				cur[j], back[i][j-lo[i]] = float64(j), moveLeft
+				continue
+			}
+			best, move := math.Inf(1), byte(0)
+			inPrev := func(j int) bool { return j >= lo[i-1] && j <= hi[i-1] }
+			if j > 0 && inPrev(j-1) {
+				best, move = prev[j-1]+sub(i-1, j-1), moveDiag
+			}
+			if j > lo[i] && cur[j-1]+1 < best {
+				best, move = cur[j-1]+1, moveLeft
+			}
+			if inPrev(j) && prev[j]+1 < best {
+				best, move = prev[j]+1, moveUp
+			}
+			cur[j], back[i][j-lo[i]] = best, move
+		}
+		prev, cur = cur, prev
+	}
+
+	words := make([]Word, len(ref))
+	matched := make([]bool, len(ref))
+	for i, j := n, m; i > 0; {
+		switch back[i][j-lo[i]] {
+		case moveDiag:
+			h := recognized[j-1]
+			words[i-1] = Word{Text: ref[i-1], Start: h.Start, End: h.End}
+			if refN[i-1] == hypN[j-1] {
+				words[i-1].P = h.P
+			}
+			matched[i-1] = true
+			i, j = i-1, j-1
+		case moveLeft:
+			j--
+		default:
+			words[i-1] = Word{Text: ref[i-1]}
+			i--
+		}
+	}
+	interpolate(words, matched, recognized)
+	return words
+}
+
+// Moves in the alignment table. Ties are broken in this order.
+const (
+	moveDiag byte = iota + 1 // match or substitute a word
+	moveLeft                 // skip a recognized word
+	moveUp                   // skip a transcript word
+)
+
+const (
+	minAlignBand      = 100
+	alignBandFraction = 20 // the band is 1/alignBandFraction of n+m
+)
+
+// alignBand returns the half-width of the band of the alignment table that
+// is computed for n transcript and m recognized words. It is wide enough
+// for every row to connect to the next.
+func alignBand(n, m int) int {
+	band := (n + m) / alignBandFraction
+	if band < minAlignBand {
+		band = minAlignBand
+	}
+	if step := (m+n-1)/n + 1; band < step {
+		band = step
+	}
+	return band
+}
+
+// interpolate assigns times to the unmatched words, spreading each run of
+// them over the gap between its matched neighbors.
+func interpolate(words []Word, matched []bool, recognized []Word) {
+	var lo, hi time.Duration
+	if len(recognized) > 0 {
+		lo, hi = recognized[0].Start, recognized[len(recognized)-1].End
+	}
+	for i := 0; i < len(words); {
+		if matched[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(words) && !matched[j] {
+			j++
+		}
+		start, end := lo, hi
+		if i > 0 {
+			start = words[i-1].End
+		}
+		if j < len(words) {
+			end = words[j].Start
+		}
+		if end < start {
+			end = start
+		}
+		var total, n int
+		for _, w := range words[i:j] {
+			total += len(w.Text)
+		}
+		span := end - start
+		for k := i; k < j; k++ {
+			words[k].Start = start + span*time.Duration(n)/time.Duration(total)
+			n += len(words[k].Text)
+			words[k].End = start + span*time.Duration(n)/time.Duration(total)
+		}
+		i = j
+	}
+}
+
+// similarity returns 1 minus the edit distance between a and b relative to
+// the longer of the two, so equal words score 1 and unrelated ones 0.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			c := prev[j-1]
+			if ra[i-1] != rb[j-1] {
+				c++
+			}
+			cur[j] = minInt(c, minInt(prev[j], cur[j-1])+1)
+		}
+		prev, cur = cur, prev
+	}
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
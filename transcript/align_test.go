@@ -0,0 +1,131 @@
+package transcript
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recognize returns the words of text as recognized words, one second
+// each, with probability 0.9.
+func recognize(text string) []Word {
+	var words []Word
+	for i, w := range strings.Fields(text) {
+		words = append(words, Word{Text: w, Start: time.Duration(i) * time.Second, End: time.Duration(i+1) * time.Second, P: 0.9})
+	}
+	return words
+}
+
+func TestAlignToRecognition(t *testing.T) {
+	s := time.Second
+	for _, tt := range []struct {
+		name       string
+		text       string
+		recognized string
+		want       []Word
+	}{
+		{
+			name:       "exact",
+			text:       "Hello, World!",
+			recognized: "hello world",
+			want:       []Word{{"Hello,", 0, s, 0.9}, {"World!", s, 2 * s, 0.9}},
+		},
+		{
+			name:       "insertion",
+			text:       "the quick brown fox",
+			recognized: "the brown fox",
+			want:       []Word{{"the", 0, s, 0.9}, {"quick", s, s, 0}, {"brown", s, 2 * s, 0.9}, {"fox", 2 * s, 3 * s, 0.9}},
+		},
+		{
+			name:       "deletion",
+			text:       "the fox",
+			recognized: "the um fox",
+			want:       []Word{{"the", 0, s, 0.9}, {"fox", 2 * s, 3 * s, 0.9}},
+		},
+		{
+			name:       "substitution",
+			text:       "the quick fox",
+			recognized: "the quack fox",
+			want:       []Word{{"the", 0, s, 0.9}, {"quick", s, 2 * s, 0}, {"fox", 2 * s, 3 * s, 0.9}},
+		},
+		{
+			name:       "missing run",
+			text:       "a bb cccc d",
+			recognized: "a x y d",
+			want:       []Word{{"a", 0, s, 0.9}, {"bb", s, 2 * s, 0}, {"cccc", 2 * s, 3 * s, 0}, {"d", 3 * s, 4 * s, 0.9}},
+		},
+		{
+			name:       "nothing recognized",
+			text:       "one two",
+			recognized: "",
+			want:       []Word{{"one", 0, 0, 0}, {"two", 0, 0, 0}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			checkWords(t, AlignToRecognition(tt.text, recognize(tt.recognized)), tt.want)
+		})
+	}
+	if words := AlignToRecognition(" \n", recognize("one")); words != nil {
+		t.Errorf("empty text: got %v, want nil", words)
+	}
+}
+
+func TestAlignToRecognitionInterpolates(t *testing.T) {
+	// Unmatched words share the gap between their neighbors in proportion
+	// to their length.
+	recognized := []Word{
+		{Text: "start", Start: 0, End: time.Second, P: 1},
+		{Text: "end", Start: 4 * time.Second, End: 5 * time.Second, P: 1},
+	}
+	got := AlignToRecognition("start a bbb end", recognized)
+	want := []Word{
+		{"start", 0, time.Second, 1},
+		{"a", time.Second, 1750 * time.Millisecond, 0},
+		{"bbb", 1750 * time.Millisecond, 4 * time.Second, 0},
+		{"end", 4 * time.Second, 5 * time.Second, 1},
+	}
+	checkWords(t, got, want)
+}
+
+func TestAlignToRecognitionLong(t *testing.T) {
+	// Long enough that only a band of the table is computed, with words
+	// missed and inserted throughout.
+	const n = 3000
+	ref := make([]string, n)
+	var rec []string
+	for i := range ref {
+		ref[i] = fmt.Sprintf("w%d", i)
+		if i%7 != 3 {
+			rec = append(rec, ref[i])
+		}
+		if i%11 == 5 {
+			rec = append(rec, "uh")
+		}
+	}
+	if band := alignBand(n, len(rec)); band >= n {
+		t.Fatalf("band %d covers the whole table", band)
+	}
+	recognized := recognize(strings.Join(rec, " "))
+	at := make(map[string]Word)
+	for _, w := range recognized {
+		at[w.Text] = w
+	}
+	words := AlignToRecognition(strings.Join(ref, " "), recognized)
+	if len(words) != n {
+		t.Fatalf("got %d words, want %d", len(words), n)
+	}
+	for i, w := range words {
+		r, ok := at[w.Text]
+		switch {
+		case w.Text != ref[i]:
+			t.Fatalf("word %d is %q, want %q", i, w.Text, ref[i])
+		case ok && (w.Start != r.Start || w.End != r.End || w.P != r.P):
+			t.Fatalf("word %q: got %+v, want the times of %+v", w.Text, w, r)
+		case !ok && w.P != 0:
+			t.Fatalf("missed word %q has probability %v", w.Text, w.P)
+		case i > 0 && w.Start < words[i-1].End:
+			t.Fatalf("word %q starts at %v, before %q ends at %v", w.Text, w.Start, words[i-1].Text, words[i-1].End)
+		}
+	}
+}
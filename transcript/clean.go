@@ -0,0 +1,181 @@
+package transcript
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// CleanOptions configures Clean. A zero threshold disables its check; see
+// DefaultCleanOptions for typical values.
+type CleanOptions struct {
+	// MinAvgProb drops segments whose text tokens have a mean probability
+	// below it.
+	MinAvgProb float32
+
+	// MaxNoSpeech drops segments whose no-speech likelihood, as reported
+	// by NoSpeech, is above it.
+	MaxNoSpeech float64
+	// NoSpeech returns the likelihood, from 0 to 1, that the audio between
+	// start and end contains no speech. The check is skipped if it is nil.
+	NoSpeech func(start, end time.Duration) float64
+
+	// DropAnnotations drops segments that consist only of bracketed
+	// non-speech annotations such as "[BLANK_AUDIO]", "(music)" or
+	// "*laughs*".
+	DropAnnotations bool
+
+	// MaxRepeats drops segments in which a phrase of up to MaxNGram words
+	// is repeated back to back more than MaxRepeats times, and segments
+	// that repeat the previous segment's text more than MaxRepeats times
+	// in a row.
+	MaxRepeats int
+	MaxNGram   int
+}
+
+// DefaultCleanOptions returns options that remove common whisper
+// hallucinations while rarely touching real speech.
+func DefaultCleanOptions() CleanOptions {
+	return CleanOptions{
+		MinAvgProb:      0.4,
+		MaxNoSpeech:     0.8,
+		DropAnnotations: true,
+		MaxRepeats:      3,
+		MaxNGram:        6,
+	}
+}
+
+// Reason is why Clean removed a segment.
+type Reason string
+
+const (
+	ReasonLowProbability Reason = "low probability"
+	ReasonNoSpeech       Reason = "no speech"
+	ReasonAnnotation     Reason = "annotation"
+	ReasonRepetition     Reason = "repetition"
+)
+
+// Removal records a segment removed by Clean.
+type Removal struct {
+	Segment Segment
+	Reason  Reason
+	Detail  string
+}
+
+// CleanReport describes what Clean removed.
+type CleanReport struct {
+	Kept    int
+	Removed []Removal
+}
+
+// Write writes a human-readable summary of the report to w.
+func (r CleanReport) Write(w io.Writer) error {
+	ew := &errWriter{w: w}
+	ew.printf("kept %d segments, removed %d\n", r.Kept, len(r.Removed))
+	for _, rm := range r.Removed {
+		ew.printf("  [%s --> %s] %q: %s (%s)\n",
+			timestamp(rm.Segment.Start, "."), timestamp(rm.Segment.End, "."),
+			strings.TrimSpace(rm.Segment.Text), rm.Reason, rm.Detail)
+	}
+	return ew.err
+}
+
+// Clean removes segments that are likely hallucinations rather than speech
+// and reports what it removed and why.
+func Clean(segments []Segment, opts CleanOptions) ([]Segment, CleanReport) {
+	var (
+		kept     []Segment
+		report   CleanReport
+		previous string
+		repeats  int
+	)
+	for _, s := range segments {
+		reason, detail := opts.check(s)
+		if reason == "" && opts.MaxRepeats > 0 {
+			text := normalize(s.Text)
+			if text != "" && text == previous {
+				repeats++
+			} else {
+				previous, repeats = text, 0
+			}
+			if repeats > opts.MaxRepeats {
+				reason, detail = ReasonRepetition, fmt.Sprintf("segment repeated %d times", repeats+1)
+			}
+		}
+		if reason != "" {
+			report.Removed = append(report.Removed, Removal{Segment: s, Reason: reason, Detail: detail})
+			continue
+		}
+		kept = append(kept, s)
+	}
+	report.Kept = len(kept)
+	return kept, report
+}
+
+// check returns why s should be removed on its own, if it should be.
+func (o CleanOptions) check(s Segment) (Reason, string) {
+	if o.DropAnnotations && isAnnotation(s.Text) {
+		return ReasonAnnotation, "non-speech annotation"
+	}
+	if o.MinAvgProb > 0 && len(s.Tokens) > 0 {
+		var sum float32
+		for _, t := range s.Tokens {
+			sum += t.P
+		}
+		if avg := sum / float32(len(s.Tokens)); avg < o.MinAvgProb {
+			return ReasonLowProbability, fmt.Sprintf("mean token probability %.2f", avg)
+		}
+	}
+	if o.MaxNoSpeech > 0 && o.NoSpeech != nil {
+		if p := o.NoSpeech(s.Start, s.End); p > o.MaxNoSpeech {
+			return ReasonNoSpeech, fmt.Sprintf("no-speech likelihood %.2f", p)
+		}
+	}
+	if o.MaxRepeats > 0 {
+		if phrase, n := repeatedNGram(strings.Fields(normalize(s.Text)), o.MaxNGram); n > o.MaxRepeats {
+			return ReasonRepetition, fmt.Sprintf("%q repeated %d times", phrase, n)
+		}
+	}
+	return "", ""
+}
+
+// annotationRE matches text made only of bracketed or starred annotations
+// and music symbols.
+var annotationRE = regexp.MustCompile(`^(\s*(\[[^\]]*\]|\([^)]*\)|\*[^*]*\*|[♪♫]+))+\s*$`)
+
+func isAnnotation(text string) bool {
+	return strings.TrimSpace(text) != "" && annotationRE.MatchString(text)
+}
+
+// normalize lowercases text and removes punctuation, so that repeated
+// phrases compare equal.
+func normalize(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	}), " ")
+}
+
+// repeatedNGram returns the phrase of up to maxN words that is repeated
+// back to back the most times in words, and the number of repetitions.
+func repeatedNGram(words []string, maxN int) (string, int) {
+	var (
+		best   string
+		bestN  int
+		phrase = func(ws []string) string { return strings.Join(ws, " ") }
+	)
+	for n := 1; n <= maxN && 2*n <= len(words); n++ {
+		for i := 0; i+n <= len(words); i++ {
+			count := 1
+			for j := i + n; j+n <= len(words) && phrase(words[j:j+n]) == phrase(words[i:i+n]); j += n {
+				count++
+			}
+			if count > bestN {
+				best, bestN = phrase(words[i:i+n]), count
+			}
+		}
+	}
+	return best, bestN
+}
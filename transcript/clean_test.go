@@ -0,0 +1,94 @@
+package transcript
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClean(t *testing.T) {
+	s := func(text string) Segment {
+		return Segment{Text: text, Tokens: []Token{{Text: text, P: 0.9}}}
+	}
+	segments := []Segment{
+		s(" So let's get started."),
+		s(" [BLANK_AUDIO]"),
+		s(" (music) ♪"),
+		s(" Thank you. Thank you. Thank you. Thank you. Thank you."),
+		s(" We'll take questions."),
+		s(" Yes, yes, that's right."),
+		s(" Okay."),
+		s(" Okay."),
+		s(" Okay."),
+		s(" Okay."),
+		s(" Okay."),
+		s(" [inaudible] but the budget is fine."),
+		{Text: " mumble", Tokens: []Token{{Text: " mumble", P: 0.1}}},
+	}
+	kept, report := Clean(segments, DefaultCleanOptions())
+
+	var got []string
+	for _, k := range kept {
+		got = append(got, strings.TrimSpace(k.Text))
+	}
+	want := []string{
+		"So let's get started.",
+		"We'll take questions.",
+		"Yes, yes, that's right.",
+		"Okay.", "Okay.", "Okay.", "Okay.",
+		"[inaudible] but the budget is fine.",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("kept:\n%q\nwant:\n%q", got, want)
+	}
+
+	reasons := map[string]Reason{}
+	for _, r := range report.Removed {
+		reasons[strings.TrimSpace(r.Segment.Text)] = r.Reason
+	}
+	for text, want := range map[string]Reason{
+		"[BLANK_AUDIO]": ReasonAnnotation,
+		"(music) ♪":     ReasonAnnotation,
+		"Thank you. Thank you. Thank you. Thank you. Thank you.": ReasonRepetition,
+		"Okay.":  ReasonRepetition,
+		"mumble": ReasonLowProbability,
+	} {
+		if reasons[text] != want {
+			t.Errorf("%q removed for %q, want %q", text, reasons[text], want)
+		}
+	}
+	if report.Kept != len(kept) || len(report.Removed) != len(segments)-len(kept) {
+		t.Errorf("report counts %d kept and %d removed, want %d and %d",
+			report.Kept, len(report.Removed), len(kept), len(segments)-len(kept))
+	}
+}
+
+func TestCleanNoSpeech(t *testing.T) {
+	segments := []Segment{
+		{Start: 0, End: time.Second, Text: " Hello."},
+		{Start: time.Second, End: 2 * time.Second, Text: " Goodbye."},
+	}
+	opts := CleanOptions{
+		MaxNoSpeech: 0.8,
+		NoSpeech: func(start, end time.Duration) float64 {
+			if start >= time.Second {
+				return 1
+			}
+			return 0
+		},
+	}
+	kept, report := Clean(segments, opts)
+	if len(kept) != 1 || kept[0].Text != " Hello." {
+		t.Errorf("kept %+v, want only the first segment", kept)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].Reason != ReasonNoSpeech {
+		t.Errorf("removed %+v, want the second segment for no speech", report.Removed)
+	}
+}
+
+func TestCleanZeroOptions(t *testing.T) {
+	segments := []Segment{{Text: " [BLANK_AUDIO]"}, {Text: " la la la la la la"}}
+	if kept, _ := Clean(segments, CleanOptions{}); len(kept) != len(segments) {
+		t.Errorf("zero options removed segments: kept %+v", kept)
+	}
+}
@@ -0,0 +1,246 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Format is an output format for segments.
+type Format string
+
+// Supported output formats.
+const (
+	FormatText Format = "txt"
+	FormatSRT  Format = "srt"
+	FormatVTT  Format = "vtt"
+	FormatJSON Format = "json"
+	FormatTSV  Format = "tsv"
+	FormatLRC  Format = "lrc"
+)
+
+// Formats lists the supported output formats.
+var Formats = []Format{FormatText, FormatSRT, FormatVTT, FormatJSON, FormatTSV, FormatLRC}
+
+// ParseFormat returns the format with the given name, which is matched
+// case-insensitively. "text" and "webvtt" are accepted as aliases.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case "text":
+		return FormatText, nil
+	case "webvtt":
+		return FormatVTT, nil
+	case FormatText, FormatSRT, FormatVTT, FormatJSON, FormatTSV, FormatLRC:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown output format %q", name)
+}
+
+// Ext returns the file extension for the format, including the dot.
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// Write renders segments to w in the given format.
+func Write(w io.Writer, f Format, segments []Segment) error {
+	switch f {
+	case FormatText:
+		return WriteText(w, segments)
+	case FormatSRT:
+		return WriteSRT(w, segments)
+	case FormatVTT:
+		return WriteVTT(w, segments)
+	case FormatJSON:
+		return WriteJSON(w, segments)
+	case FormatTSV:
+		return WriteTSV(w, segments)
+	case FormatLRC:
+		return WriteLRC(w, segments)
+	}
+	return fmt.Errorf("unknown output format %q", f)
+}
+
+// WriteText writes the concatenated text of the segments. If the segments
+// have speakers, each speaker's turn is written on its own line, prefixed
+// with the speaker's label.
+func WriteText(w io.Writer, segments []Segment) error {
+	if !HasSpeakers(segments) {
+		_, err := fmt.Fprintln(w, Text(segments))
+		return err
+	}
+	ew := &errWriter{w: w}
+	for i := 0; i < len(segments); {
+		j := i + 1
+		for j < len(segments) && segments[j].Speaker == segments[i].Speaker {
+			j++
+		}
+		ew.printf("%s: %s\n", SpeakerLabel(segments[i].Speaker), strings.TrimSpace(Text(segments[i:j])))
+		i = j
+	}
+	return ew.err
+}
+
+// speakerText returns the trimmed text of s, prefixed with its speaker's
+// label if labeled is set.
+func speakerText(s Segment, labeled bool) string {
+	text := strings.TrimSpace(s.Text)
+	if !labeled {
+		return text
+	}
+	return SpeakerLabel(s.Speaker) + ": " + text
+}
+
+// WriteSRT writes the segments as SubRip subtitles.
+func WriteSRT(w io.Writer, segments []Segment) error {
+	ew := &errWriter{w: w}
+	labeled := HasSpeakers(segments)
+	for i, s := range segments {
+		ew.printf("%d\n%s --> %s\n%s\n\n", i+1,
+			timestamp(s.Start, ","), timestamp(s.End, ","), speakerText(s, labeled))
+	}
+	return ew.err
+}
+
+// WriteVTT writes the segments as WebVTT subtitles. Speakers are marked
+// with voice tags.
+func WriteVTT(w io.Writer, segments []Segment) error {
+	ew := &errWriter{w: w}
+	ew.printf("WEBVTT\n\n")
+	labeled := HasSpeakers(segments)
+	for _, s := range segments {
+		text := strings.TrimSpace(s.Text)
+		if labeled {
+			text = fmt.Sprintf("<v %s>%s", SpeakerLabel(s.Speaker), text)
+		}
+		ew.printf("%s --> %s\n%s\n\n",
+			timestamp(s.Start, "."), timestamp(s.End, "."), text)
+	}
+	return ew.err
+}
+
+// WriteTSV writes the segments as tab-separated start and end times in
+// milliseconds followed by the text. If the segments have speakers, a
+// speaker column precedes the text.
+func WriteTSV(w io.Writer, segments []Segment) error {
+	ew := &errWriter{w: w}
+	labeled := HasSpeakers(segments)
+	if labeled {
+		ew.printf("start\tend\tspeaker\ttext\n")
+	} else {
+		ew.printf("start\tend\ttext\n")
+	}
+	for _, s := range segments {
+		text := strings.NewReplacer("\t", " ", "\n", " ").Replace(strings.TrimSpace(s.Text))
+		if labeled {
+			ew.printf("%d\t%d\t%d\t%s\n", s.Start.Milliseconds(), s.End.Milliseconds(), s.Speaker, text)
+			continue
+		}
+		ew.printf("%d\t%d\t%s\n", s.Start.Milliseconds(), s.End.Milliseconds(), text)
+	}
+	return ew.err
+}
+
+// WriteLRC writes the segments as LRC lyrics.
+func WriteLRC(w io.Writer, segments []Segment) error {
+	ew := &errWriter{w: w}
+	labeled := HasSpeakers(segments)
+	for _, s := range segments {
+		cs := s.Start.Milliseconds() / 10
+		ew.printf("[%02d:%02d.%02d]%s\n", cs/6000, cs/100%60, cs%100, speakerText(s, labeled))
+	}
+	return ew.err
+}
+
+// jsonToken and jsonSegment are the JSON forms of Token and Segment, with
+// times in seconds.
+type jsonToken struct {
+	Token
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+type jsonSegment struct {
+	Num    int         `json:"num"`
+	Start  float64     `json:"start"`
+	End    float64     `json:"end"`
+	Text   string      `json:"text"`
+	Tokens []jsonToken `json:"tokens,omitempty"`
+
+	Speaker int `json:"speaker,omitempty"`
+}
+
+// WriteJSON writes the segments, including their tokens, timings, token
+// probabilities and speakers, as a JSON document. Times are in seconds.
+func WriteJSON(w io.Writer, segments []Segment) error {
+	out := struct {
+		Text     string        `json:"text"`
+		Segments []jsonSegment `json:"segments"`
+	}{
+		Text:     strings.TrimSpace(Text(segments)),
+		Segments: make([]jsonSegment, len(segments)),
+	}
+	for i, s := range segments {
+		js := jsonSegment{
+			Num: s.Num, Start: s.Start.Seconds(), End: s.End.Seconds(), Text: s.Text,
+			Speaker: s.Speaker,
+		}
+		for _, t := range s.Tokens {
+			js.Tokens = append(js.Tokens, jsonToken{Token: t, Start: t.Start.Seconds(), End: t.End.Seconds()})
+		}
+		out.Segments[i] = js
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// ReadJSON reads segments written by WriteJSON.
+func ReadJSON(r io.Reader) ([]Segment, error) {
+	var in struct {
+		Segments []jsonSegment `json:"segments"`
+	}
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return nil, fmt.Errorf("could not decode transcript: %w", err)
+	}
+	segments := make([]Segment, len(in.Segments))
+	for i, js := range in.Segments {
+		s := Segment{
+			Num: js.Num, Start: seconds(js.Start), End: seconds(js.End), Text: js.Text,
+			Speaker: js.Speaker,
+		}
+		for _, jt := range js.Tokens {
+			t := jt.Token
+			t.Start, t.End = seconds(jt.Start), seconds(jt.End)
+			s.Tokens = append(s.Tokens, t)
+		}
+		segments[i] = s
+	}
+	return segments, nil
+}
+
+// seconds converts a time in seconds to a Duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
+
+// timestamp formats d as hh:mm:ss followed by sep and milliseconds.
+func timestamp(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// errWriter records the first write error.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
@@ -0,0 +1,107 @@
+package transcript
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimestamp(t *testing.T) {
+	for _, tt := range []struct {
+		d        time.Duration
+		srt, vtt string
+	}{
+		{0, "00:00:00,000", "00:00:00.000"},
+		{62345 * time.Millisecond, "00:01:02,345", "00:01:02.345"},
+		{time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, "01:02:03,004", "01:02:03.004"},
+		{123*time.Hour + 45*time.Minute + 6*time.Second + 789*time.Millisecond, "123:45:06,789", "123:45:06.789"},
+		{1999 * time.Microsecond, "00:00:00,001", "00:00:00.001"},
+	} {
+		if got := timestamp(tt.d, ","); got != tt.srt {
+			t.Errorf("SRT timestamp of %v = %q, want %q", tt.d, got, tt.srt)
+		}
+		if got := timestamp(tt.d, "."); got != tt.vtt {
+			t.Errorf("VTT timestamp of %v = %q, want %q", tt.d, got, tt.vtt)
+		}
+	}
+}
+
+var testSegments = []Segment{
+	{Num: 0, Start: 0, End: 1500 * time.Millisecond, Text: " Hello there."},
+	{Num: 1, Start: 62345 * time.Millisecond, End: 64 * time.Second, Text: " General\tKenobi."},
+}
+
+func TestWrite(t *testing.T) {
+	speakers := append([]Segment(nil), testSegments...)
+	speakers[0].Speaker, speakers[1].Speaker = 1, 2
+	for _, tt := range []struct {
+		name     string
+		format   Format
+		segments []Segment
+		want     string
+	}{
+		{"text", FormatText, testSegments, " Hello there. General\tKenobi.\n"},
+		{"srt", FormatSRT, testSegments,
+			"1\n00:00:00,000 --> 00:00:01,500\nHello there.\n\n" +
+				"2\n00:01:02,345 --> 00:01:04,000\nGeneral\tKenobi.\n\n"},
+		{"vtt", FormatVTT, testSegments,
+			"WEBVTT\n\n00:00:00.000 --> 00:00:01.500\nHello there.\n\n" +
+				"00:01:02.345 --> 00:01:04.000\nGeneral\tKenobi.\n\n"},
+		{"tsv", FormatTSV, testSegments, "start\tend\ttext\n0\t1500\tHello there.\n62345\t64000\tGeneral Kenobi.\n"},
+		{"lrc", FormatLRC, testSegments, "[00:00.00]Hello there.\n[01:02.34]General\tKenobi.\n"},
+		{"text speakers", FormatText, speakers, "Speaker 1: Hello there.\nSpeaker 2: General\tKenobi.\n"},
+		{"srt speakers", FormatSRT, speakers[:1], "1\n00:00:00,000 --> 00:00:01,500\nSpeaker 1: Hello there.\n\n"},
+		{"vtt speakers", FormatVTT, speakers[:1], "WEBVTT\n\n00:00:00.000 --> 00:00:01.500\n<v Speaker 1>Hello there.\n\n"},
+		{"tsv speakers", FormatTSV, speakers[:1], "start\tend\tspeaker\ttext\n0\t1500\t1\tHello there.\n"},
+	} {
+		var b bytes.Buffer
+		if err := Write(&b, tt.format, tt.segments); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s: got\n%q\nwant\n%q", tt.name, b.String(), tt.want)
+		}
+	}
+}
+
+func TestLRCLongRecording(t *testing.T) {
+	// LRC minutes are not wrapped into hours.
+	var b bytes.Buffer
+	WriteLRC(&b, []Segment{{Start: 2*time.Hour + 5*time.Minute + 7*time.Second + 890*time.Millisecond, Text: " late"}})
+	if want := "[125:07.89]late\n"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	segments := []Segment{
+		{Num: 0, Start: 0, End: 1500 * time.Millisecond, Text: " Hello.", Speaker: 1, Tokens: []Token{
+			{ID: 1, Text: " Hello", P: 0.75, Start: 0, End: time.Second},
+			{ID: 2, Text: ".", P: 0.5, Start: time.Second, End: 1500 * time.Millisecond},
+		}},
+		{Num: 1, Start: 2 * time.Second, End: 3250 * time.Millisecond, Text: " Hi.", Speaker: 2},
+	}
+	var b bytes.Buffer
+	if err := WriteJSON(&b, segments); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadJSON(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, segments) {
+		t.Errorf("round trip changed segments:\ngot  %+v\nwant %+v", got, segments)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]Format{"txt": FormatText, "TEXT": FormatText, "webvtt": FormatVTT, "Srt": FormatSRT} {
+		if got, err := ParseFormat(name); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("doc"); err == nil {
+		t.Error("ParseFormat(\"doc\"): no error")
+	}
+}
@@ -0,0 +1,63 @@
+// Package transcript holds speech recognition results and renders them in
+// text, subtitle and structured formats.
+package transcript
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Token is a text token within a segment.
+type Token struct {
+	ID         int           `json:"id"`
+	Text       string        `json:"text"`
+	P          float32       `json:"p"`
+	Start, End time.Duration `json:"-"`
+}
+
+// Timed reports whether t has times of its own. Whisper gives tokens
+// negative times when token timestamps are not enabled.
+func (t Token) Timed() bool {
+	return t.Start >= 0 && t.End >= t.Start && t.End != 0
+}
+
+// Segment is a timed span of recognized text.
+type Segment struct {
+	Num        int
+	Start, End time.Duration
+	Text       string
+	Tokens     []Token
+
+	// Speaker identifies the speaker of the segment, starting at 1, or is
+	// 0 if unknown.
+	Speaker int
+}
+
+// Text returns the concatenated text of the segments.
+func Text(segments []Segment) string {
+	var b strings.Builder
+	for _, s := range segments {
+		b.WriteString(s.Text)
+	}
+	return b.String()
+}
+
+// HasSpeakers reports whether any of the segments has a known speaker.
+func HasSpeakers(segments []Segment) bool {
+	for _, s := range segments {
+		if s.Speaker != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SpeakerLabel returns the display name of a speaker id, e.g. "Speaker 1",
+// or "Unknown" for 0.
+func SpeakerLabel(speaker int) string {
+	if speaker == 0 {
+		return "Unknown"
+	}
+	return fmt.Sprintf("Speaker %d", speaker)
+}
@@ -0,0 +1,144 @@
+package transcript
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Word is a timed word assembled from tokens.
+type Word struct {
+	Text       string
+	Start, End time.Duration
+	P          float32 // Mean probability of the word's tokens
+}
+
+// Words assembles the text tokens of the segments into words. A token that
+// begins with a space starts a new word, punctuation is attached to the
+// word before it, and special tokens such as "[_BEG_]" or "<|endoftext|>"
+// are skipped.
+//
+// Token times are used when they are known, which requires the transcriber
+// to produce token timestamps; otherwise each segment's span is divided
+// among its tokens in proportion to their length.
+func Words(segments []Segment) []Word {
+	var words []Word
+	for _, s := range segments {
+		tokens := timedTokens(s)
+		var (
+			cur   *Word
+			count int
+		)
+		for _, t := range tokens {
+			text := t.Text
+			if cur == nil || (strings.HasPrefix(text, " ") && !isPunct(text)) {
+				if cur != nil {
+					cur.P /= float32(count)
+				}
+				words = append(words, Word{Start: t.Start})
+				cur, count = &words[len(words)-1], 0
+				text = strings.TrimLeft(text, " ")
+			}
+			cur.Text += text
+			cur.End = t.End
+			cur.P += t.P
+			count++
+		}
+		if cur != nil {
+			cur.P /= float32(count)
+		}
+	}
+	return words
+}
+
+// timedTokens returns the tokens of s, dividing the segment's span among
+// them if they have no times of their own.
+func timedTokens(s Segment) []Token {
+	tokens := make([]Token, 0, len(s.Tokens))
+	timed := true
+	for _, t := range s.Tokens {
+		if strings.TrimSpace(t.Text) == "" || isSpecial(t.Text) {
+			continue
+		}
+		if !t.Timed() {
+			timed = false
+		}
+		tokens = append(tokens, t)
+	}
+	if timed {
+		return tokens
+	}
+	var total int
+	for _, t := range tokens {
+		total += len(t.Text)
+	}
+	var n int
+	span := s.End - s.Start
+	for i := range tokens {
+		tokens[i].Start = s.Start + span*time.Duration(n)/time.Duration(total)
+		n += len(tokens[i].Text)
+		tokens[i].End = s.Start + span*time.Duration(n)/time.Duration(total)
+	}
+	return tokens
+}
+
+// isSpecial reports whether text is the text of a whisper special token,
+// such as "[_TT_150]" or "<|en|>".
+func isSpecial(text string) bool {
+	return strings.HasPrefix(text, "[_") && strings.HasSuffix(text, "]") ||
+		strings.HasPrefix(text, "<|") && strings.HasSuffix(text, "|>")
+}
+
+// isPunct reports whether text consists only of spaces and punctuation.
+func isPunct(text string) bool {
+	return strings.TrimFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) == ""
+}
+
+// WriteWords renders words to w in the given format: as JSON with
+// WriteWordsJSON, as TSV with WriteWordsTSV, and in other formats as
+// segments of one word each.
+func WriteWords(w io.Writer, f Format, words []Word) error {
+	switch f {
+	case FormatJSON:
+		return WriteWordsJSON(w, words)
+	case FormatTSV:
+		return WriteWordsTSV(w, words)
+	}
+	segments := make([]Segment, len(words))
+	for i, wd := range words {
+		segments[i] = Segment{Num: i, Start: wd.Start, End: wd.End, Text: " " + wd.Text}
+	}
+	return Write(w, f, segments)
+}
+
+// WriteWordsTSV writes the words as tab-separated start and end times in
+// milliseconds, probability and text.
+func WriteWordsTSV(w io.Writer, words []Word) error {
+	ew := &errWriter{w: w}
+	ew.printf("start\tend\tp\tword\n")
+	for _, wd := range words {
+		ew.printf("%d\t%d\t%.3f\t%s\n", wd.Start.Milliseconds(), wd.End.Milliseconds(), wd.P, wd.Text)
+	}
+	return ew.err
+}
+
+// WriteWordsJSON writes the words as a JSON array. Times are in seconds.
+func WriteWordsJSON(w io.Writer, words []Word) error {
+	type jsonWord struct {
+		Text  string  `json:"text"`
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+		P     float32 `json:"p"`
+	}
+	out := make([]jsonWord, len(words))
+	for i, wd := range words {
+		out[i] = jsonWord{Text: wd.Text, Start: wd.Start.Seconds(), End: wd.End.Seconds(), P: wd.P}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,95 @@
+package transcript
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func tok(text string, start, end time.Duration, p float32) Token {
+	return Token{Text: text, Start: start, End: end, P: p}
+}
+
+func TestWords(t *testing.T) {
+	ms := time.Millisecond
+	segments := []Segment{
+		{Start: 0, End: time.Second, Tokens: []Token{
+			tok("[_BEG_]", 0, 0, 1),
+			tok(" Hel", 0, 200*ms, 0.8),
+			tok("lo", 200*ms, 300*ms, 0.6),
+			tok(",", 300*ms, 320*ms, 0.9),
+			tok(" world", 400*ms, 900*ms, 0.5),
+			tok(".", 900*ms, 950*ms, 1),
+			tok("<|endoftext|>", 950*ms, time.Second, 1),
+		}},
+		{Start: time.Second, End: 2 * time.Second, Tokens: []Token{
+			// The first token of a transcript may lack its space.
+			tok("Again", time.Second, 1500*ms, 0.7),
+			tok(" ", 1500*ms, 1500*ms, 1),
+		}},
+	}
+	want := []Word{
+		{Text: "Hello,", Start: 0, End: 320 * ms, P: (0.8 + 0.6 + 0.9) / 3},
+		{Text: "world.", Start: 400 * ms, End: 950 * ms, P: (0.5 + 1) / 2},
+		{Text: "Again", Start: time.Second, End: 1500 * ms, P: 0.7},
+	}
+	checkWords(t, Words(segments), want)
+}
+
+func TestWordsUntimed(t *testing.T) {
+	// Without token timestamps, the segment's span is divided among its
+	// tokens in proportion to their length.
+	untimed := func(text string) Token { return tok(text, -10*time.Millisecond, -10*time.Millisecond, 1) }
+	segments := []Segment{{Start: 2 * time.Second, End: 4 * time.Second, Tokens: []Token{
+		untimed(" ab"), untimed("c"), untimed(" de"), untimed("[_TT_100]"),
+	}}}
+	split := 2*time.Second + 2*time.Second*4/7
+	want := []Word{
+		{Text: "abc", Start: 2 * time.Second, End: split, P: 1},
+		{Text: "de", Start: split, End: 4 * time.Second, P: 1},
+	}
+	checkWords(t, Words(segments), want)
+}
+
+func TestWriteWords(t *testing.T) {
+	words := []Word{
+		{Text: "one", Start: 0, End: 500 * time.Millisecond, P: 0.5},
+		{Text: "two", Start: 500 * time.Millisecond, End: 1250 * time.Millisecond, P: 1},
+	}
+	for _, tt := range []struct {
+		format Format
+		want   string
+	}{
+		{FormatTSV, "start\tend\tp\tword\n0\t500\t0.500\tone\n500\t1250\t1.000\ttwo\n"},
+		{FormatText, " one two\n"},
+		{FormatLRC, "[00:00.00]one\n[00:00.50]two\n"},
+	} {
+		var b bytes.Buffer
+		if err := WriteWords(&b, tt.format, words); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, b.String(), tt.want)
+		}
+	}
+}
+
+func checkWords(t *testing.T, got, want []Word) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d words %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Text != w.Text || g.Start != w.Start || g.End != w.End || abs32(g.P-w.P) > 1e-6 {
+			t.Errorf("word %d: got %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func abs32(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,167 @@
+package wavutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/go-audio/wav"
+)
+
+const wavFormatFloat = 3
+
+// LoadWAV reads the named WAV file and returns its samples mixed down to
+// mono, along with the sample rate.
+func LoadWAV(filename string) ([]float32, int, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not open file: %w", err)
+	}
+	defer f.Close()
+	return ReadWAV(f)
+}
+
+// LoadWAVChannels is like LoadWAV but returns the samples of each channel
+// separately.
+func LoadWAVChannels(filename string) ([][]float32, int, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not open file: %w", err)
+	}
+	defer f.Close()
+	return ReadWAVChannels(f)
+}
+
+// ReadWAV reads a WAV file from r and returns its samples mixed down to
+// mono, along with the sample rate. Integer PCM of 8 to 32 bits and 32-bit
+// float data are supported.
+func ReadWAV(r io.ReadSeeker) ([]float32, int, error) {
+	channels, sampleRate, err := ReadWAVChannels(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return Mix(channels), sampleRate, nil
+}
+
+// ReadWAVChannels is like ReadWAV but returns the samples of each channel
+// separately.
+func ReadWAVChannels(r io.ReadSeeker) ([][]float32, int, error) {
+	d := wav.NewDecoder(r)
+	if !d.IsValidFile() {
+		return nil, 0, errors.New("not a valid wav file")
+	}
+	buf, err := d.FullPCMBuffer()
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not decode wav file: %w", err)
+	}
+
+	var toFloat func(int) float32
+	switch {
+	case d.WavAudioFormat == wavFormatFloat && d.BitDepth == 32:
+		toFloat = func(v int) float32 { return math.Float32frombits(uint32(v)) }
+	case d.BitDepth == 8:
+		toFloat = func(v int) float32 { return float32(v-128) / 128 }
+	default:
+		scale := float32(int64(1) << (d.BitDepth - 1))
+		toFloat = func(v int) float32 { return float32(v) / scale }
+	}
+
+	n := int(d.NumChans)
+	if n < 1 {
+		n = 1
+	}
+	channels := make([][]float32, n)
+	for c := range channels {
+		channels[c] = make([]float32, len(buf.Data)/n)
+		for i := range channels[c] {
+			channels[c][i] = toFloat(buf.Data[i*n+c])
+		}
+	}
+	return channels, int(d.SampleRate), nil
+}
+
+// Mix mixes channels of equal length down to mono by averaging them.
+func Mix(channels [][]float32) []float32 {
+	if len(channels) == 1 {
+		return channels[0]
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+	data := make([]float32, len(channels[0]))
+	for i := range data {
+		var sum float32
+		for _, ch := range channels {
+			sum += ch[i]
+		}
+		data[i] = sum / float32(len(channels))
+	}
+	return data
+}
+
+// PCMFormat describes headerless PCM audio.
+type PCMFormat struct {
+	SampleRate int
+	Channels   int
+	Encoding   string // One of "u8", "s16le", "s24le", "s32le" or "f32le"
+}
+
+// ReadPCM reads headerless PCM audio in the given format from r and returns
+// its samples mixed down to mono.
+func ReadPCM(r io.Reader, format PCMFormat) ([]float32, error) {
+	var (
+		size    int
+		decode  func([]byte) float32
+		channel = format.Channels
+	)
+	switch format.Encoding {
+	case "u8":
+		size = 1
+		decode = func(b []byte) float32 { return float32(int(b[0])-128) / 128 }
+	case "s16le":
+		size = 2
+		decode = func(b []byte) float32 { return float32(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) }
+	case "s24le":
+		size = 3
+		decode = func(b []byte) float32 {
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			return float32(v) / (1 << 23)
+		}
+	case "s32le":
+		size = 4
+		decode = func(b []byte) float32 { return float32(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }
+	case "f32le":
+		size = 4
+		decode = func(b []byte) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(b)) }
+	default:
+		return nil, fmt.Errorf("unsupported pcm encoding %q", format.Encoding)
+	}
+	if format.SampleRate <= 0 {
+		return nil, fmt.Errorf("invalid pcm sample rate %d", format.SampleRate)
+	}
+	if channel <= 0 {
+		return nil, fmt.Errorf("invalid pcm channel count %d", channel)
+	}
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read pcm data: %w", err)
+	}
+	frame := size * channel
+	data := make([]float32, len(raw)/frame)
+	rd := bytes.NewReader(raw)
+	b := make([]byte, size)
+	for i := range data {
+		var sum float32
+		for c := 0; c < channel; c++ {
+			io.ReadFull(rd, b)
+			sum += decode(b)
+		}
+		data[i] = sum / float32(channel)
+	}
+	return data, nil
+}
@@ -0,0 +1,64 @@
+package wavutil
+
+import "math"
+
+// resampleTaps is the number of zero crossings of the windowed sinc kernel
+// on each side of a sample.
+const resampleTaps = 16
+
+// Resample converts data from one sample rate to another using windowed
+// sinc interpolation. When downsampling, the kernel is widened so that it
+// also acts as an anti-aliasing filter.
+func Resample(data []float32, from, to int) []float32 {
+	if from == to || len(data) == 0 {
+		return data
+	}
+	ratio := float64(to) / float64(from)
+	out := make([]float32, int(float64(len(data))*ratio))
+
+	// cutoff relative to the input sample rate
+	cutoff := 1.0
+	if ratio < 1 {
+		cutoff = ratio
+	}
+	width := float64(resampleTaps) / cutoff
+
+	for i := range out {
+		center := float64(i) / ratio
+		lo := int(math.Ceil(center - width))
+		hi := int(math.Floor(center + width))
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(data)-1 {
+			hi = len(data) - 1
+		}
+		var sum, norm float64
+		for j := lo; j <= hi; j++ {
+			x := float64(j) - center
+			w := cutoff * sinc(cutoff*x) * blackman(x/width)
+			sum += w * float64(data[j])
+			norm += w
+		}
+		if norm != 0 {
+			out[i] = float32(sum / norm)
+		}
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman evaluates a Blackman window over x in [-1, 1].
+func blackman(x float64) float64 {
+	if x < -1 || x > 1 {
+		return 0
+	}
+	t := math.Pi * (x + 1)
+	return 0.42 - 0.5*math.Cos(t) + 0.08*math.Cos(2*t)
+}
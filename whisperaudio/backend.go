@@ -0,0 +1,94 @@
+package whisperaudio
+
+import (
+	"sync"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// audioBackend is the audio library used for capture. It is a variable so
+// that tests can substitute a fake.
+var backend audioBackend = portaudioBackend{}
+
+// audioBackend abstracts the audio library.
+type audioBackend interface {
+	Initialize() error
+	Terminate() error
+	// OpenInputStream opens a blocking input stream that fills buf on
+	// each Read.
+	OpenInputStream(channels int, sampleRate float64, buf []float32) (inputStream, error)
+	// Devices lists the available devices.
+	Devices() ([]Device, error)
+}
+
+// inputStream is a blocking audio input stream.
+type inputStream interface {
+	Start() error
+	Stop() error
+	Read() error
+	Close() error
+}
+
+// portaudioBackend implements audioBackend with portaudio.
+type portaudioBackend struct{}
+
+func (portaudioBackend) Initialize() error { return portaudio.Initialize() }
+func (portaudioBackend) Terminate() error  { return portaudio.Terminate() }
+
+func (portaudioBackend) OpenInputStream(channels int, sampleRate float64, buf []float32) (inputStream, error) {
+	s, err := portaudio.OpenDefaultStream(channels, 0, sampleRate, len(buf)/channels, buf)
+	if err != nil {
+		return nil, err
+	}
+	return portaudioStream{s}, nil
+}
+
+// portaudioStream adapts a portaudio.Stream to inputStream.
+type portaudioStream struct {
+	*portaudio.Stream
+}
+
+// Read reads a buffer, reporting input overflow as errInputOverflowed.
+func (s portaudioStream) Read() error {
+	if err := s.Stream.Read(); err != portaudio.InputOverflowed {
+		return err
+	}
+	return errInputOverflowed
+}
+
+func (portaudioBackend) Devices() ([]Device, error) {
+	return portaudioDevices()
+}
+
+var (
+	backendMu   sync.Mutex
+	backendRefs int
+)
+
+// acquireBackend initializes the audio backend on first use. Each successful
+// call must be matched by a call to releaseBackend.
+func acquireBackend() error {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	if backendRefs == 0 {
+		if err := backend.Initialize(); err != nil {
+			return err
+		}
+	}
+	backendRefs++
+	return nil
+}
+
+// releaseBackend terminates the audio backend when its last user releases it.
+func releaseBackend() error {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	if backendRefs == 0 {
+		return nil
+	}
+	backendRefs--
+	if backendRefs == 0 {
+		return backend.Terminate()
+	}
+	return nil
+}
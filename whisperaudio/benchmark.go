@@ -0,0 +1,45 @@
+package whisperaudio
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/tmc/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+// benchmarkDuration is the length of the synthetic audio used by
+// BenchmarkModel.
+const benchmarkDuration = 5 * time.Second
+
+// BenchmarkModel measures the real-time factor of the model at modelPath by
+// transcribing a few seconds of synthetic audio. It is suitable for use as
+// whisperutil.ModelRequirements.Benchmark.
+func BenchmarkModel(modelPath string) (float64, error) {
+	model, err := whisper.New(modelPath)
+	if err != nil {
+		return 0, fmt.Errorf("could not initialize model: %w", err)
+	}
+	defer model.Close()
+	mctx, err := model.NewContext()
+	if err != nil {
+		return 0, fmt.Errorf("could not initialize context: %w", err)
+	}
+
+	// A quiet tone with some noise keeps the decoder busy without
+	// producing long hallucinated output.
+	n := int(benchmarkDuration.Seconds() * whisper.SampleRate)
+	buf := make([]float32, n)
+	rng := rand.New(rand.NewSource(1))
+	for i := range buf {
+		t := float64(i) / whisper.SampleRate
+		buf[i] = float32(0.1*math.Sin(2*math.Pi*220*t) + 0.01*rng.NormFloat64())
+	}
+
+	t0 := time.Now()
+	if err := mctx.Process(buf, nil, nil); err != nil {
+		return 0, fmt.Errorf("could not process audio: %w", err)
+	}
+	return time.Since(t0).Seconds() / benchmarkDuration.Seconds(), nil
+}
@@ -0,0 +1,184 @@
+package whisperaudio
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tmc/audioutil/ringbuf"
+	"github.com/tmc/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+const (
+	// ringDuration is the amount of audio buffered between the capture
+	// goroutine and consumers.
+	ringDuration = 30 * time.Second
+
+	// eventBuffer is the number of capture events buffered for consumers.
+	eventBuffer = 16
+)
+
+// Recording states, see WhisperAudio.recording.
+const (
+	recIdle     = iota // not recording
+	recStarting        // Start was called; the capture goroutine has not yet begun the recording
+	recActive          // recording into the ring buffer
+)
+
+// errInputOverflowed is returned by inputStream.Read when the device
+// discarded input because it was not read in time. The data that was read
+// is still valid.
+var errInputOverflowed = errors.New("input overflowed")
+
+// CaptureEvent reports a problem in the capture goroutine.
+type CaptureEvent struct {
+	Time          time.Time
+	Dropped       int   // Samples dropped because the ring buffer was full
+	InputOverflow bool  // The device discarded input before it was read
+	Err           error // A read error that stopped capture
+}
+
+// CaptureStats are the counters of the capture goroutine.
+type CaptureStats struct {
+	ringbuf.Stats
+	InputOverflows uint64 // Reads on which the device reported lost input
+	Underruns      uint64 // Calls to Read while recording that found fewer samples than requested
+}
+
+// capture reads from the stream until stop is closed or a read fails, then
+// records the error and closes done. While recording, audio goes to the ring
+// buffer; otherwise it goes to the pre-roll buffer, if any. It runs in its own
+// goroutine so that consumers never block the audio device.
+func (wa *WhisperAudio) capture(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if err := wa.stream.Read(); errors.Is(err, errInputOverflowed) {
+			wa.inputOverflows.Add(1)
+			wa.emit(CaptureEvent{Time: time.Now(), InputOverflow: true})
+		} else if err != nil {
+			err = fmt.Errorf("could not read from stream: %w", err)
+			wa.emit(CaptureEvent{Time: time.Now(), Err: err})
+			wa.captureErr = err
+			return
+		}
+		state := wa.recording.Load()
+		if state == recStarting && wa.recording.CompareAndSwap(recStarting, recActive) {
+			wa.beginRecording()
+			state = recActive
+		}
+		recording := state == recActive
+		dropped := 0
+		var level Level
+		if recording {
+			level = wa.meter.Load().Measure(wa.inBuffer)
+		}
+		if f := wa.filter.Load(); f != nil {
+			(*f).Process(wa.inBuffer)
+		}
+		if recording {
+			dropped = len(wa.inBuffer) - wa.ring.Write(wa.inBuffer)
+		} else if h := wa.preRoll.Load(); h != nil {
+			h.write(wa.inBuffer)
+		}
+		if dropped > 0 {
+			wa.emit(CaptureEvent{Time: time.Now(), Dropped: dropped})
+		}
+		if recording {
+			select {
+			case wa.ready <- struct{}{}:
+			default:
+			}
+			select {
+			case wa.levels <- level:
+			default:
+			}
+		}
+	}
+}
+
+// beginRecording discards the audio of the last recording and starts the
+// new one with the pre-roll audio, if any.
+func (wa *WhisperAudio) beginRecording() {
+	wa.ring.Reset()
+	if h := wa.preRoll.Load(); h != nil {
+		wa.ring.Write(h.take())
+	}
+	wa.meter.Load().Reset()
+}
+
+// emit sends ev to the events channel without blocking.
+func (wa *WhisperAudio) emit(ev CaptureEvent) {
+	select {
+	case wa.events <- ev:
+	default:
+	}
+}
+
+// Events returns a channel of capture problems such as dropped samples.
+// Events are discarded if the channel is not drained.
+func (wa *WhisperAudio) Events() <-chan CaptureEvent {
+	return wa.events
+}
+
+// Stats returns the capture counters.
+func (wa *WhisperAudio) Stats() CaptureStats {
+	return CaptureStats{
+		Stats:          wa.ring.Stats(),
+		InputOverflows: wa.inputOverflows.Load(),
+		Underruns:      wa.underruns.Load(),
+	}
+}
+
+// Read copies up to len(p) captured samples into p without blocking and
+// returns the number copied. A call that finds fewer samples than len(p)
+// while recording is counted as an underrun.
+func (wa *WhisperAudio) Read(p []float32) int {
+	n := wa.ring.Read(p)
+	if n < len(p) && wa.recording.Load() == recActive {
+		wa.underruns.Add(1)
+	}
+	return n
+}
+
+// ReadAvailable returns all captured samples that have not been read yet.
+func (wa *WhisperAudio) ReadAvailable() []float32 {
+	buf := make([]float32, wa.ring.Len())
+	return buf[:wa.ring.Read(buf)]
+}
+
+// CollectAudioData waits for and returns the given duration of recorded
+// audio. Recording must have been started with Start. If recording stops
+// first, the audio recorded so far is returned with an error.
+func (wa *WhisperAudio) CollectAudioData(duration time.Duration) ([]float32, error) {
+	if wa.stopped == nil {
+		return nil, errors.New("recording not started")
+	}
+	buf := make([]float32, int(duration.Seconds()*whisper.SampleRate))
+	for n := 0; n < len(buf); {
+		n += wa.ring.Read(buf[n:])
+		if n == len(buf) {
+			break
+		}
+		var err error
+		select {
+		case <-wa.ready:
+			continue
+		case <-wa.captureDone:
+			err = wa.captureErr
+			if err == nil {
+				err = errors.New("stream stopped")
+			}
+		case <-wa.stopped:
+			err = errors.New("recording stopped")
+		}
+		// Return what is buffered.
+		n += wa.ring.Read(buf[n:])
+		return buf[:n], err
+	}
+	return buf, nil
+}
@@ -0,0 +1,30 @@
+package whisperaudio
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tmc/audioutil/chunk"
+	"github.com/tmc/audioutil/transcript"
+)
+
+// ChunkError reports the chunks that could not be transcribed by
+// TranscribeChunked.
+type ChunkError struct {
+	Chunks []chunk.Chunk // The failed chunks
+	Errs   []error       // The error for each failed chunk
+}
+
+func (e *ChunkError) Error() string {
+	msgs := make([]string, len(e.Chunks))
+	for i, c := range e.Chunks {
+		msgs[i] = fmt.Sprintf("chunk %d at %v: %v", c.Index, c.Start, e.Errs[i])
+	}
+	return fmt.Sprintf("%d chunks failed: %s", len(e.Chunks), strings.Join(msgs, "; "))
+}
+
+// TranscribeChunked transcribes long audio in chunks; see
+// Transcriber.TranscribeChunked.
+func (wa *WhisperAudio) TranscribeChunked(buf []float32, opts chunk.Options) ([]transcript.Segment, error) {
+	return wa.transcriber.TranscribeChunked(buf, opts)
+}
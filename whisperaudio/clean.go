@@ -0,0 +1,54 @@
+package whisperaudio
+
+import (
+	"math"
+	"time"
+
+	"github.com/tmc/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+// The whisper.cpp Go bindings do not expose whisper's own no-speech
+// probability, so it is estimated from the audio instead.
+
+// noSpeechFrame is the length of the frames whose levels NoSpeech measures.
+const noSpeechFrame = 20 * time.Millisecond
+
+// DefaultSilenceLevel is the RMS level in dBFS below which NoSpeech
+// considers audio to be silent.
+const DefaultSilenceLevel = -45
+
+// NoSpeech returns a function, suitable for transcript.CleanOptions, that
+// estimates the likelihood that a span of buf contains no speech as the
+// fraction of its 20ms frames quieter than silence dBFS.
+func NoSpeech(buf []float32, silence float64) func(start, end time.Duration) float64 {
+	frame := int(noSpeechFrame.Seconds() * whisper.SampleRate)
+	threshold := math.Pow(10, silence/20)
+	quiet := make([]bool, len(buf)/frame)
+	for i := range quiet {
+		var sum float64
+		for _, s := range buf[i*frame : (i+1)*frame] {
+			sum += float64(s) * float64(s)
+		}
+		quiet[i] = math.Sqrt(sum/float64(frame)) < threshold
+	}
+	return func(start, end time.Duration) float64 {
+		lo := int(start / noSpeechFrame)
+		hi := int((end + noSpeechFrame - 1) / noSpeechFrame)
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(quiet) {
+			hi = len(quiet)
+		}
+		if hi <= lo {
+			return 1
+		}
+		n := 0
+		for _, q := range quiet[lo:hi] {
+			if q {
+				n++
+			}
+		}
+		return float64(n) / float64(hi-lo)
+	}
+}
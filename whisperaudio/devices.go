@@ -0,0 +1,104 @@
+package whisperaudio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// Device describes an audio device.
+type Device struct {
+	Index             int     `json:"index"`
+	Name              string  `json:"name"`
+	HostAPI           string  `json:"host_api"`
+	MaxInputChannels  int     `json:"max_input_channels"`
+	MaxOutputChannels int     `json:"max_output_channels"`
+	DefaultSampleRate float64 `json:"default_sample_rate"`
+
+	DefaultLowInputLatency   time.Duration `json:"default_low_input_latency"`
+	DefaultLowOutputLatency  time.Duration `json:"default_low_output_latency"`
+	DefaultHighInputLatency  time.Duration `json:"default_high_input_latency"`
+	DefaultHighOutputLatency time.Duration `json:"default_high_output_latency"`
+
+	IsDefaultInput  bool `json:"is_default_input"`
+	IsDefaultOutput bool `json:"is_default_output"`
+}
+
+// Devices returns the audio devices available on the system.
+func Devices() ([]Device, error) {
+	if err := acquireBackend(); err != nil {
+		return nil, fmt.Errorf("could not initialize portaudio: %w", err)
+	}
+	defer releaseBackend()
+	return backend.Devices()
+}
+
+// portaudioDevices lists the devices known to portaudio.
+func portaudioDevices() ([]Device, error) {
+	infos, err := portaudio.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("could not get devices: %w", err)
+	}
+	// A missing default device is not an error when listing devices.
+	defIn, _ := portaudio.DefaultInputDevice()
+	defOut, _ := portaudio.DefaultOutputDevice()
+
+	devices := make([]Device, len(infos))
+	for i, d := range infos {
+		devices[i] = Device{
+			Index:                    i,
+			Name:                     d.Name,
+			MaxInputChannels:         d.MaxInputChannels,
+			MaxOutputChannels:        d.MaxOutputChannels,
+			DefaultSampleRate:        d.DefaultSampleRate,
+			DefaultLowInputLatency:   d.DefaultLowInputLatency,
+			DefaultLowOutputLatency:  d.DefaultLowOutputLatency,
+			DefaultHighInputLatency:  d.DefaultHighInputLatency,
+			DefaultHighOutputLatency: d.DefaultHighOutputLatency,
+			IsDefaultInput:           d == defIn,
+			IsDefaultOutput:          d == defOut,
+		}
+		if d.HostApi != nil {
+			devices[i].HostAPI = d.HostApi.Name
+		}
+	}
+	return devices, nil
+}
+
+// WriteDeviceTable writes devices to w as an aligned table.
+func WriteDeviceTable(w io.Writer, devices []Device) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tNAME\tHOST API\tIN\tOUT\tRATE\tLATENCY IN (LOW/HIGH)\tLATENCY OUT (LOW/HIGH)\tDEFAULT")
+	for _, d := range devices {
+		def := ""
+		switch {
+		case d.IsDefaultInput && d.IsDefaultOutput:
+			def = "in,out"
+		case d.IsDefaultInput:
+			def = "in"
+		case d.IsDefaultOutput:
+			def = "out"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%.0f Hz\t%.2f/%.2f ms\t%.2f/%.2f ms\t%s\n",
+			d.Index, d.Name, d.HostAPI, d.MaxInputChannels, d.MaxOutputChannels, d.DefaultSampleRate,
+			ms(d.DefaultLowInputLatency), ms(d.DefaultHighInputLatency),
+			ms(d.DefaultLowOutputLatency), ms(d.DefaultHighOutputLatency), def)
+	}
+	return tw.Flush()
+}
+
+// WriteDeviceJSON writes devices to w as a JSON array. Latencies are in
+// nanoseconds.
+func WriteDeviceJSON(w io.Writer, devices []Device) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(devices)
+}
+
+func ms(d time.Duration) float64 {
+	return d.Seconds() * 1000
+}
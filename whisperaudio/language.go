@@ -0,0 +1,120 @@
+package whisperaudio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/tmc/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+// detectWindow is how much audio language detection looks at, whisper's
+// input window.
+const detectWindow = 30 * time.Second
+
+// DefaultMinLanguageProb is the probability the most likely language needs
+// for automatic language selection to use it.
+const DefaultMinLanguageProb = 0.5
+
+// LanguageProb is the probability that audio is spoken in a language.
+type LanguageProb struct {
+	Language string // Language code, e.g. "en"
+	P        float32
+}
+
+// LanguageOptions configures automatic language selection.
+type LanguageOptions struct {
+	// Default is the language used when no language is likely enough,
+	// e.g. "en".
+	Default string
+	// MinProb is the probability the most likely language needs to be
+	// used instead of Default (default DefaultMinLanguageProb).
+	MinProb float32
+}
+
+// Choose returns the most likely of the ranked languages, or Default if
+// its probability is below MinProb.
+func (o LanguageOptions) Choose(ranked []LanguageProb) string {
+	threshold := o.MinProb
+	if threshold <= 0 {
+		threshold = DefaultMinLanguageProb
+	}
+	if len(ranked) == 0 || ranked[0].P < threshold {
+		return o.Default
+	}
+	return ranked[0].Language
+}
+
+// languageDetector is implemented by the contexts of the whisper.cpp Go
+// bindings, but is not part of whisper.Context.
+type languageDetector interface {
+	WhisperLangAutoDetect(offsetMs, threads int) ([]float32, error)
+}
+
+// DetectLanguage returns the languages the model supports, ranked by the
+// probability that the given audio data is spoken in them. Only the first
+// 30 seconds are used. The model must be multilingual.
+func (t *Transcriber) DetectLanguage(buf []float32) ([]LanguageProb, error) {
+	return t.DetectLanguageContext(context.Background(), buf)
+}
+
+// DetectLanguageContext is like DetectLanguage but gives up waiting for a
+// transcription in progress to finish when ctx is done.
+func (t *Transcriber) DetectLanguageContext(ctx context.Context, buf []float32) ([]LanguageProb, error) {
+	buf = t.filter(buf)
+	select {
+	case t.busy <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-t.busy }()
+	return t.detectLanguage(buf)
+}
+
+// detectLanguage detects the language of buf, which has already been
+// filtered. The caller must hold t.busy.
+func (t *Transcriber) detectLanguage(buf []float32) ([]LanguageProb, error) {
+	if !t.model.IsMultilingual() {
+		return nil, whisper.ErrModelNotMultilingual
+	}
+	if len(buf) == 0 {
+		return nil, errors.New("no audio to detect the language of")
+	}
+	if n := int(detectWindow.Seconds() * whisper.SampleRate); len(buf) > n {
+		buf = buf[:n]
+	}
+	mctx, err := t.model.NewContext()
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize context: %w", err)
+	}
+	detector, ok := mctx.(languageDetector)
+	if !ok {
+		return nil, errors.New("whisper bindings do not support language detection")
+	}
+
+	// Detection works on the mel spectrogram held by the model, which only
+	// Process computes. Asked to process less than a second, Process
+	// returns right after computing it, without decoding.
+	mctx.SetDuration(10 * time.Millisecond)
+	if err := mctx.Process(buf, nil, nil); err != nil {
+		return nil, fmt.Errorf("could not compute spectrogram: %w", err)
+	}
+	probs, err := detector.WhisperLangAutoDetect(0, runtime.NumCPU())
+	if err != nil {
+		return nil, fmt.Errorf("could not detect language: %w", err)
+	}
+
+	// Languages lists the model's languages in order of their ids, which
+	// index probs.
+	var ranked []LanguageProb
+	for id, lang := range t.model.Languages() {
+		if id < len(probs) {
+			ranked = append(ranked, LanguageProb{Language: lang, P: probs[id]})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].P > ranked[j].P })
+	return ranked, nil
+}
@@ -0,0 +1,197 @@
+package whisperaudio
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// MinDBFS is the level reported for digital silence.
+const MinDBFS = -120
+
+// levelBuffer is the number of levels buffered for consumers.
+const levelBuffer = 16
+
+// Warning identifies a sustained problem with the input level.
+type Warning int
+
+const (
+	NoWarning Warning = iota
+	WarnSilence
+	WarnClipping
+)
+
+func (w Warning) String() string {
+	switch w {
+	case NoWarning:
+		return "none"
+	case WarnSilence:
+		return "input is silent"
+	case WarnClipping:
+		return "input is clipping"
+	}
+	return fmt.Sprintf("Warning(%d)", int(w))
+}
+
+// Level is the level of one block of captured audio. Levels are in dBFS,
+// where 0 is full scale.
+type Level struct {
+	Time    time.Time
+	RMS     float64 // RMS level of the block
+	Peak    float64 // Peak level of the block
+	Clipped int     // Samples in the block at or above the clip level
+	VU      float64 // RMS level smoothed over MeterOptions.VUTime
+
+	// Warning is set on the block in which the input has been silent or
+	// clipping for the configured duration. It is set once per episode.
+	Warning Warning
+}
+
+func (l Level) String() string {
+	s := fmt.Sprintf("rms %.1f dBFS, peak %.1f dBFS, vu %.1f dBFS", l.RMS, l.Peak, l.VU)
+	if l.Clipped > 0 {
+		s += fmt.Sprintf(", %d clipped", l.Clipped)
+	}
+	if l.Warning != NoWarning {
+		s += ", " + l.Warning.String()
+	}
+	return s
+}
+
+// Bar renders the VU level as a bar of the given width, spanning -60 dBFS
+// to full scale. It is suitable for a terminal status line.
+func (l Level) Bar(width int) string {
+	n := int(math.Round((l.VU + 60) / 60 * float64(width)))
+	if n < 0 {
+		n = 0
+	} else if n > width {
+		n = width
+	}
+	return "[" + strings.Repeat("#", n) + strings.Repeat(" ", width-n) + "]"
+}
+
+// MeterOptions configures level metering. Zero fields take the defaults
+// noted below.
+type MeterOptions struct {
+	// VUTime is the integration time of the VU level (default 300ms).
+	VUTime time.Duration
+	// SilenceLevel is the RMS level below which a block is silent
+	// (default -60 dBFS).
+	SilenceLevel float64
+	// SilenceAfter is how long the input must be silent before
+	// WarnSilence is reported (default 3s).
+	SilenceAfter time.Duration
+	// ClipLevel is the sample magnitude at or above which a sample counts
+	// as clipped (default 0.999).
+	ClipLevel float32
+	// ClipAfter is how long blocks must keep clipping before WarnClipping
+	// is reported (default 500ms).
+	ClipAfter time.Duration
+}
+
+func (o MeterOptions) withDefaults() MeterOptions {
+	if o.VUTime <= 0 {
+		o.VUTime = 300 * time.Millisecond
+	}
+	if o.SilenceLevel == 0 {
+		o.SilenceLevel = -60
+	}
+	if o.SilenceAfter <= 0 {
+		o.SilenceAfter = 3 * time.Second
+	}
+	if o.ClipLevel <= 0 {
+		o.ClipLevel = 0.999
+	}
+	if o.ClipAfter <= 0 {
+		o.ClipAfter = 500 * time.Millisecond
+	}
+	return o
+}
+
+// Meter measures the level of successive blocks of audio.
+type Meter struct {
+	opts       MeterOptions
+	sampleRate int
+
+	vu         float64 // smoothed mean square
+	silentFor  time.Duration
+	clippedFor time.Duration
+}
+
+// NewMeter returns a Meter for audio at the given sample rate.
+func NewMeter(sampleRate int, opts MeterOptions) *Meter {
+	return &Meter{opts: opts.withDefaults(), sampleRate: sampleRate}
+}
+
+// Reset clears the smoothed level and the warning state.
+func (m *Meter) Reset() {
+	m.vu, m.silentFor, m.clippedFor = 0, 0, 0
+}
+
+// Measure returns the level of block, which follows the previously measured
+// block.
+func (m *Meter) Measure(block []float32) Level {
+	l := Level{Time: time.Now()}
+	if len(block) == 0 {
+		l.RMS, l.Peak, l.VU = MinDBFS, MinDBFS, dbfs(math.Sqrt(m.vu))
+		return l
+	}
+	var sum, peak float64
+	for _, s := range block {
+		a := math.Abs(float64(s))
+		sum += a * a
+		if a > peak {
+			peak = a
+		}
+		if float32(a) >= m.opts.ClipLevel {
+			l.Clipped++
+		}
+	}
+	ms := sum / float64(len(block))
+	dur := time.Duration(len(block)) * time.Second / time.Duration(m.sampleRate)
+	alpha := 1 - math.Exp(-dur.Seconds()/m.opts.VUTime.Seconds())
+	m.vu += alpha * (ms - m.vu)
+
+	l.RMS = dbfs(math.Sqrt(ms))
+	l.Peak = dbfs(peak)
+	l.VU = dbfs(math.Sqrt(m.vu))
+
+	if l.RMS < m.opts.SilenceLevel {
+		if m.silentFor < m.opts.SilenceAfter && m.silentFor+dur >= m.opts.SilenceAfter {
+			l.Warning = WarnSilence
+		}
+		m.silentFor += dur
+	} else {
+		m.silentFor = 0
+	}
+	if l.Clipped > 0 {
+		if m.clippedFor < m.opts.ClipAfter && m.clippedFor+dur >= m.opts.ClipAfter {
+			l.Warning = WarnClipping
+		}
+		m.clippedFor += dur
+	} else {
+		m.clippedFor = 0
+	}
+	return l
+}
+
+// dbfs converts an amplitude relative to full scale to decibels, clamped
+// at MinDBFS.
+func dbfs(a float64) float64 {
+	if a <= 0 {
+		return MinDBFS
+	}
+	return math.Max(20*math.Log10(a), MinDBFS)
+}
+
+// SetMeterOptions configures the level meter used while recording.
+func (wa *WhisperAudio) SetMeterOptions(opts MeterOptions) {
+	wa.meter.Store(NewMeter(wa.meter.Load().sampleRate, opts))
+}
+
+// Levels returns a channel that receives the level of each block of audio
+// while recording. Levels are discarded if the channel is not drained.
+func (wa *WhisperAudio) Levels() <-chan Level {
+	return wa.levels
+}
@@ -0,0 +1,86 @@
+package whisperaudio
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tmc/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+// SetPreRoll keeps the audio stream running between recordings and retains
+// the last d of audio in a rolling buffer. Start prepends that audio to the
+// recording, so speech that begins just before Start is not lost. A
+// duration of zero disables pre-roll and stops the stream while not
+// recording. The duration may be at most 30 seconds.
+//
+// Because the stream keeps running, the microphone stays open while
+// pre-roll is enabled, and the operating system shows it as in use, e.g.
+// with the recording indicator in the macOS menu bar, even between
+// recordings.
+func (wa *WhisperAudio) SetPreRoll(d time.Duration) error {
+	if wa.closed {
+		return errClosed
+	}
+	if d < 0 || d > ringDuration {
+		return fmt.Errorf("pre-roll of %v out of range [0, %v]", d, ringDuration)
+	}
+
+	if d == 0 {
+		wa.preRoll.Store(nil)
+	} else {
+		wa.preRoll.Store(newHistory(int(d.Seconds() * whisper.SampleRate)))
+	}
+
+	if wa.started {
+		// The new setting takes effect when recording stops.
+		return nil
+	}
+	if d > 0 && !wa.streaming {
+		return wa.startStream()
+	}
+	if d == 0 && wa.streaming {
+		return wa.stopStream()
+	}
+	return nil
+}
+
+// history is a fixed-size buffer that keeps the most recently written
+// samples, overwriting the oldest. It is only used by the capture goroutine.
+type history struct {
+	buf  []float32
+	pos  int // index of the next write
+	full bool
+}
+
+func newHistory(size int) *history {
+	return &history{buf: make([]float32, size)}
+}
+
+// write appends p, discarding the oldest samples once the buffer is full.
+func (h *history) write(p []float32) {
+	if len(p) >= len(h.buf) {
+		copy(h.buf, p[len(p)-len(h.buf):])
+		h.pos, h.full = 0, true
+		return
+	}
+	n := copy(h.buf[h.pos:], p)
+	if n < len(p) {
+		copy(h.buf, p[n:])
+		h.full = true
+	}
+	h.pos = (h.pos + len(p)) % len(h.buf)
+	if h.pos == 0 {
+		h.full = true
+	}
+}
+
+// take returns the buffered samples, oldest first, and empties the buffer.
+func (h *history) take() []float32 {
+	var out []float32
+	if h.full {
+		out = append(out, h.buf[h.pos:]...)
+	}
+	out = append(out, h.buf[:h.pos]...)
+	h.pos, h.full = 0, false
+	return out
+}
@@ -0,0 +1,268 @@
+package whisperaudio
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/tmc/audioutil/chunk"
+	"github.com/tmc/audioutil/dsp"
+	"github.com/tmc/audioutil/transcript"
+	"github.com/tmc/audioutil/whisperutil"
+	"github.com/tmc/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+// Transcriber transcribes audio with a whisper model that is loaded once and
+// shared. It is safe for use by multiple goroutines, but transcriptions run
+// one at a time: contexts created from one model share its decoder state in
+// the whisper.cpp Go bindings, so callers wait for the transcription in
+// progress to finish.
+type Transcriber struct {
+	// Configure, if set, is applied to each context before use, e.g. to set
+	// the language or thread count.
+	Configure func(whisper.Context) error
+	// Progress, if set, receives the processing progress in percent.
+	Progress whisper.ProgressCallback
+	// NewFilter, if set, returns a filter that preprocesses the audio of
+	// each transcription. A new filter is made for every call because
+	// filters carry state.
+	NewFilter func() dsp.Filter
+	// Clean, if set, removes likely hallucinations from each result with
+	// transcript.Clean. If its NoSpeech is nil, the no-speech likelihood
+	// is estimated from the audio with NoSpeech.
+	Clean *transcript.CleanOptions
+	// OnClean, if set, receives the report of each cleaning.
+	OnClean func(transcript.CleanReport)
+	// AutoLanguage, if set, detects the language of each transcription
+	// with DetectLanguage and transcribes in the language it chooses,
+	// overriding any language set by Configure. It has no effect with
+	// English-only models.
+	AutoLanguage *LanguageOptions
+	// OnLanguage, if set, receives the language chosen by AutoLanguage
+	// and the ranked languages it was chosen from.
+	OnLanguage func(chosen string, ranked []LanguageProb)
+	// WordTimestamps enables token timestamps, which give the tokens of
+	// each segment, and so transcript.Words, their own times.
+	WordTimestamps bool
+
+	model whisper.Model
+	busy  chan struct{} // held while the model's decoder state is in use
+}
+
+// NewTranscriber loads the model selected by opts and returns a Transcriber
+// for it.
+func NewTranscriber(opts ...whisperutil.Option) (*Transcriber, error) {
+	modelPath, err := whisperutil.GetModelPath(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not get model path: %w", err)
+	}
+	if _, err := whisperutil.InspectModel(modelPath); err != nil {
+		return nil, fmt.Errorf("could not validate model: %w", err)
+	}
+	model, err := whisper.New(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize model: %w", err)
+	}
+	return NewTranscriberFromModel(model), nil
+}
+
+// NewTranscriberFromModel returns a Transcriber using an already loaded
+// model. The Transcriber takes ownership of the model and closes it in
+// Close.
+func NewTranscriberFromModel(model whisper.Model) *Transcriber {
+	return &Transcriber{
+		model: model,
+		busy:  make(chan struct{}, 1),
+	}
+}
+
+// Model returns the underlying whisper model.
+func (t *Transcriber) Model() whisper.Model {
+	return t.model
+}
+
+// EnableClean makes t drop likely hallucinations, such as "Thank you." on
+// silence, using the default CleanOptions, and write a report of each
+// removal to w. If w is nil, removals are not reported.
+func (t *Transcriber) EnableClean(w io.Writer) {
+	clean := transcript.DefaultCleanOptions()
+	t.Clean = &clean
+	t.OnClean = nil
+	if w != nil {
+		t.OnClean = func(r transcript.CleanReport) {
+			if len(r.Removed) > 0 {
+				r.Write(w)
+			}
+		}
+	}
+}
+
+// Close releases the model. It must not be called while transcriptions are
+// running.
+func (t *Transcriber) Close() error {
+	return t.model.Close()
+}
+
+// Transcribe transcribes the given audio data and returns the timed segments.
+func (t *Transcriber) Transcribe(buf []float32) ([]transcript.Segment, error) {
+	return t.TranscribeContext(context.Background(), buf)
+}
+
+// TranscribeContext is like Transcribe but gives up waiting for another
+// transcription to finish when ctx is done.
+func (t *Transcriber) TranscribeContext(ctx context.Context, buf []float32) ([]transcript.Segment, error) {
+	return t.transcribe(ctx, t.filter(buf), t.WordTimestamps)
+}
+
+// TranscribeWords transcribes the given audio data with token timestamps,
+// regardless of WordTimestamps, and returns the timed words.
+func (t *Transcriber) TranscribeWords(buf []float32) ([]transcript.Word, error) {
+	segments, err := t.transcribe(context.Background(), t.filter(buf), true)
+	if err != nil {
+		return nil, err
+	}
+	return transcript.Words(segments), nil
+}
+
+// AlignToRecognition returns the words of text, a known transcript of the
+// given audio data, with times taken from the words recognized in it. See
+// transcript.AlignToRecognition.
+func (t *Transcriber) AlignToRecognition(buf []float32, text string) ([]transcript.Word, error) {
+	words, err := t.TranscribeWords(buf)
+	if err != nil {
+		return nil, err
+	}
+	return transcript.AlignToRecognition(text, words), nil
+}
+
+// filter returns buf preprocessed by a new filter from NewFilter, or buf
+// itself if NewFilter is nil. The filter sees the audio in the same blocks
+// as captured audio, so that it behaves the same on both.
+func (t *Transcriber) filter(buf []float32) []float32 {
+	if t.NewFilter == nil {
+		return buf
+	}
+	return dsp.Apply(t.NewFilter(), buf, bufferSize)
+}
+
+// transcribe transcribes buf, which has already been filtered, with token
+// timestamps if words is set. Empty audio has no segments; the bindings
+// cannot process it.
+func (t *Transcriber) transcribe(ctx context.Context, buf []float32, words bool) ([]transcript.Segment, error) {
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	select {
+	case t.busy <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-t.busy }()
+
+	mctx, err := t.model.NewContext()
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize context: %w", err)
+	}
+	mctx.SetTokenTimestamps(words)
+	if t.Configure != nil {
+		if err := t.Configure(mctx); err != nil {
+			return nil, fmt.Errorf("could not configure context: %w", err)
+		}
+	}
+	if t.AutoLanguage != nil && t.model.IsMultilingual() {
+		ranked, err := t.detectLanguage(buf)
+		if err != nil {
+			return nil, err
+		}
+		lang := t.AutoLanguage.Choose(ranked)
+		if t.OnLanguage != nil {
+			t.OnLanguage(lang, ranked)
+		}
+		if lang != "" {
+			if err := mctx.SetLanguage(lang); err != nil {
+				return nil, fmt.Errorf("could not set language %q: %w", lang, err)
+			}
+		}
+	}
+	segments, err := t.process(mctx, buf)
+	if err != nil || t.Clean == nil {
+		return segments, err
+	}
+	opts := *t.Clean
+	if opts.NoSpeech == nil {
+		opts.NoSpeech = NoSpeech(buf, DefaultSilenceLevel)
+	}
+	segments, report := transcript.Clean(segments, opts)
+	if t.OnClean != nil {
+		t.OnClean(report)
+	}
+	return segments, nil
+}
+
+// process runs mctx over buf and collects the segments.
+func (t *Transcriber) process(mctx whisper.Context, buf []float32) ([]transcript.Segment, error) {
+	if err := mctx.Process(buf, nil, t.Progress); err != nil {
+		return nil, fmt.Errorf("could not process audio: %w", err)
+	}
+	var segments []transcript.Segment
+	for {
+		s, err := mctx.NextSegment()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("could not get next segment: %w", err)
+		}
+		segments = append(segments, toSegment(mctx, s))
+	}
+	return segments, nil
+}
+
+// toSegment converts a whisper segment, dropping special tokens.
+func toSegment(mctx whisper.Context, s whisper.Segment) transcript.Segment {
+	seg := transcript.Segment{Num: s.Num, Start: s.Start, End: s.End, Text: s.Text}
+	for _, t := range s.Tokens {
+		if !mctx.IsText(t) {
+			continue
+		}
+		seg.Tokens = append(seg.Tokens, transcript.Token{
+			ID: t.Id, Text: t.Text, P: t.P, Start: t.Start, End: t.End,
+		})
+	}
+	return seg
+}
+
+// TranscribeChunked transcribes long audio by splitting it into chunks with
+// chunk.Split, transcribing them in turn, and stitching the results with
+// absolute timestamps. If some chunks fail, the segments of the others are
+// returned along with a *ChunkError.
+func (t *Transcriber) TranscribeChunked(buf []float32, opts chunk.Options) ([]transcript.Segment, error) {
+	if opts.SampleRate == 0 {
+		opts.SampleRate = whisper.SampleRate
+	}
+	// Filter the whole input so that filter state carries across chunks.
+	chunks := chunk.Split(t.filter(buf), opts)
+	results := make([][]transcript.Segment, len(chunks))
+
+	var cerr *ChunkError
+	for i, c := range chunks {
+		segments, err := t.transcribe(context.Background(), c.Data, t.WordTimestamps)
+		if err != nil {
+			if cerr == nil {
+				cerr = &ChunkError{}
+			}
+			cerr.Chunks = append(cerr.Chunks, c)
+			cerr.Errs = append(cerr.Errs, err)
+			continue
+		}
+		if segments == nil {
+			segments = []transcript.Segment{}
+		}
+		results[i] = segments
+	}
+	segments := chunk.Stitch(chunks, results)
+	if cerr != nil {
+		return segments, cerr
+	}
+	return segments, nil
+}
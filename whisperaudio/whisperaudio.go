@@ -1,12 +1,14 @@
 package whisperaudio
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"os"
-	"time"
+	"sync/atomic"
 
-	"github.com/gordonklaus/portaudio"
+	"github.com/tmc/audioutil/dsp"
+	"github.com/tmc/audioutil/ringbuf"
+	"github.com/tmc/audioutil/transcript"
 	"github.com/tmc/audioutil/whisperutil"
 	"github.com/tmc/whisper.cpp/bindings/go/pkg/whisper"
 )
@@ -16,112 +18,204 @@ const (
 	bufferSize = 2048
 )
 
+var errClosed = errors.New("whisperaudio: use of closed WhisperAudio")
+
 // WhisperAudio is a wrapper around the whisper library and portaudio.
 type WhisperAudio struct {
-	model    whisper.Model
-	mctx     whisper.Context
-	stream   *portaudio.Stream
-	inBuffer []float32
+	transcriber *Transcriber
+	stream      inputStream
+	inBuffer    []float32
+	started     bool // recording, between Start and Stop
+	streaming   bool // the stream and capture goroutine are running
+	closed      bool
+
+	// capture state, see capture.go, preroll.go and level.go; the capture
+	// goroutine reads the atomic fields once per block
+	recording      atomic.Int32 // one of recIdle, recStarting and recActive
+	preRoll        atomic.Pointer[history]
+	meter          atomic.Pointer[Meter]
+	filter         atomic.Pointer[dsp.Filter]
+	levels         chan Level
+	ring           *ringbuf.Ring
+	ready          chan struct{} // signalled when samples are written
+	stopped        chan struct{} // closed by Stop
+	events         chan CaptureEvent
+	stopCapture    chan struct{}
+	captureDone    chan struct{} // closed when the capture goroutine exits
+	captureErr     error         // set before captureDone is closed
+	inputOverflows atomic.Uint64
+	underruns      atomic.Uint64
 }
 
 // New creates a new WhisperAudio instance.
 func New(opts ...whisperutil.Option) (*WhisperAudio, error) {
-	// Initialize portaudio
-	if err := portaudio.Initialize(); err != nil {
-		return nil, fmt.Errorf("could not initialize portaudio: %w", err)
-	}
-
 	// Initialize whisper model
-	modelPath, err := whisperutil.GetModelPath(opts...)
+	transcriber, err := NewTranscriber(opts...)
 	if err != nil {
-		return nil, fmt.Errorf("could not get model path: %w", err)
+		return nil, err
 	}
-
-	model, err := whisper.New(modelPath)
+	transcriber.Progress = func(p int) {
+		if p <= 100 {
+			fmt.Fprintf(os.Stderr, "progress: %d%%\n", p)
+		}
+	}
+	wa, err := newWithTranscriber(transcriber)
 	if err != nil {
-		return nil, fmt.Errorf("could not initialize model: %w", err)
+		transcriber.Close()
+		return nil, err
 	}
+	return wa, nil
+}
 
-	mctx, err := model.NewContext()
-	if err != nil {
-		return nil, fmt.Errorf("could not initialize context: %w", err)
+// newWithTranscriber initializes the audio backend and opens the input
+// stream of a WhisperAudio that transcribes with t.
+func newWithTranscriber(t *Transcriber) (*WhisperAudio, error) {
+	// Initialize portaudio
+	if err := acquireBackend(); err != nil {
+		return nil, fmt.Errorf("could not initialize portaudio: %w", err)
 	}
-	//mctx.SetLanguage("en")
-	//mctx.SetSpeedup(true)
 
 	// Open audio stream
 	in := make([]float32, bufferSize*channels)
-	stream, err := portaudio.OpenDefaultStream(channels, 0, whisper.SampleRate, bufferSize, in)
+	stream, err := backend.OpenInputStream(channels, whisper.SampleRate, in)
 	if err != nil {
+		releaseBackend()
 		return nil, fmt.Errorf("could not open default stream: %w", err)
 	}
 
 	// Create WhisperAudio instance
-	return &WhisperAudio{
-		model:    model,
-		mctx:     mctx,
-		stream:   stream,
-		inBuffer: in,
-	}, nil
+	wa := &WhisperAudio{
+		transcriber: t,
+		stream:      stream,
+		inBuffer:    in,
+		ring:        ringbuf.New(int(ringDuration.Seconds() * whisper.SampleRate)),
+		ready:       make(chan struct{}, 1),
+		events:      make(chan CaptureEvent, eventBuffer),
+		levels:      make(chan Level, levelBuffer),
+	}
+	wa.meter.Store(NewMeter(whisper.SampleRate, MeterOptions{}))
+	return wa, nil
 }
 
-// DumpDeviceInfo dumps the device info.
-func DumpDeviceInfo() {
-	portaudio.Initialize()
+// Close stops and closes the audio stream, releases the model and
+// terminates portaudio once no other WhisperAudio is using it. It is safe to
+// call Close more than once.
+func (wa *WhisperAudio) Close() error {
+	if wa.closed {
+		return nil
+	}
+	wa.closed = true
 
-	fmt.Fprintln(os.Stderr, "default input device:")
-	in, err := portaudio.DefaultInputDevice()
-	if err != nil {
-		panic(err)
+	var errs []error
+	if wa.started {
+		if err := wa.Stop(); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	fmt.Fprintf(os.Stderr, "%+v\n", in)
-	fmt.Fprintln(os.Stderr, "default output device:")
-	out, err := portaudio.DefaultOutputDevice()
-	if err != nil {
-		panic(err)
+	if wa.streaming {
+		if err := wa.stopStream(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := wa.stream.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("could not close stream: %w", err))
+	}
+	if err := wa.transcriber.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("could not close model: %w", err))
+	}
+	if err := releaseBackend(); err != nil {
+		errs = append(errs, fmt.Errorf("could not terminate portaudio: %w", err))
 	}
-	fmt.Fprintf(os.Stderr, "%+v\n", out)
+	return errors.Join(errs...)
+}
 
-	fmt.Fprintln(os.Stderr, "devices:")
-	devices, err := portaudio.Devices()
+// DumpDeviceInfo prints the available devices to stderr.
+//
+// Deprecated: Use Devices and WriteDeviceTable, which report errors
+// instead of printing them.
+func DumpDeviceInfo() {
+	devices, err := Devices()
 	if err != nil {
-		panic(err)
-	}
-	for _, d := range devices {
-		fmt.Println(d)
+		fmt.Fprintln(os.Stderr, "could not list devices:", err)
+		return
 	}
+	WriteDeviceTable(os.Stderr, devices)
 }
 
-// Start starts the audio stream.
+// Start starts recording. Unless pre-roll is enabled, it starts the audio
+// stream and the goroutine that captures it into a ring buffer, and audio
+// captured before Start is discarded. With pre-roll, the buffered pre-roll
+// audio becomes the start of the recording.
 func (wa *WhisperAudio) Start() error {
-	var err error
-	wa.mctx, err = wa.model.NewContext()
-	if err != nil {
-		return fmt.Errorf("could not initialize context: %w", err)
+	if wa.closed {
+		return errClosed
 	}
-	if err := wa.stream.Start(); err != nil {
-		return fmt.Errorf("could not start stream: %w", err)
+	if wa.started {
+		return nil
+	}
+	if wa.streaming {
+		// Restart a stream whose capture goroutine failed.
+		select {
+		case <-wa.captureDone:
+			wa.stopStream()
+		default:
+		}
 	}
-	return nil
-}
 
-// CollectAudioData collects audio data for the given duration.
-func (wa *WhisperAudio) CollectAudioData(duration time.Duration) ([]float32, error) {
-	// TODO: don't truncate to seconds.
-	seconds := int(duration.Seconds())
-	buf := make([]float32, 0, seconds*(whisper.SampleRate/bufferSize))
-	for i := 0; i < seconds*(whisper.SampleRate/bufferSize); i++ {
-		err := wa.stream.Read()
-		if err != nil {
-			return nil, fmt.Errorf("could not read from stream: %w", err)
+	if !wa.streaming {
+		// Without a capture goroutine to race with, discard the audio of
+		// the last recording now rather than on the first block.
+		wa.ring.Reset()
+	}
+	// The capture goroutine starts the recording with the pre-roll audio
+	// on its next block.
+	wa.recording.Store(recStarting)
+	if !wa.streaming {
+		if err := wa.startStream(); err != nil {
+			wa.recording.Store(recIdle)
+			return err
 		}
-		buf = append(buf, wa.inBuffer...)
 	}
-	return buf, nil
+	wa.started = true
+	wa.stopped = make(chan struct{})
+	return nil
 }
 
-// Stop stops the audio stream.
+// Stop stops recording. Unless pre-roll is enabled, it also stops the
+// capture goroutine and the audio stream. Audio recorded before Stop remains
+// available to Read.
 func (wa *WhisperAudio) Stop() error {
+	if !wa.started {
+		return nil
+	}
+	wa.started = false
+	wa.recording.Store(recIdle)
+	keepStreaming := wa.preRoll.Load() != nil
+	close(wa.stopped)
+	if keepStreaming {
+		return nil
+	}
+	return wa.stopStream()
+}
+
+// startStream starts the audio stream and the capture goroutine.
+func (wa *WhisperAudio) startStream() error {
+	if err := wa.stream.Start(); err != nil {
+		return fmt.Errorf("could not start stream: %w", err)
+	}
+	wa.streaming = true
+	wa.stopCapture = make(chan struct{})
+	wa.captureDone = make(chan struct{})
+	wa.captureErr = nil
+	go wa.capture(wa.stopCapture, wa.captureDone)
+	return nil
+}
+
+// stopStream stops the capture goroutine and the audio stream.
+func (wa *WhisperAudio) stopStream() error {
+	wa.streaming = false
+	close(wa.stopCapture)
+	<-wa.captureDone
 	if err := wa.stream.Stop(); err != nil {
 		return fmt.Errorf("could not stop stream: %w", err)
 	}
@@ -130,23 +224,53 @@ func (wa *WhisperAudio) Stop() error {
 
 // Transcribe transcribes the given audio data.
 func (wa *WhisperAudio) Transcribe(buf []float32) (string, error) {
-	if err := wa.mctx.Process(buf, nil, func(p int) {
-		if p <= 100 {
-			fmt.Fprintf(os.Stderr, "progress: %d%%\n", p)
-		}
-	}); err != nil {
-		return "", fmt.Errorf("could not process audio: %w", err)
-	}
-	result := ""
-	for {
-		s, err := wa.mctx.NextSegment()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return "", fmt.Errorf("could not get next segment: %w", err)
-		}
-		result += s.Text
+	segments, err := wa.TranscribeSegments(buf)
+	if err != nil {
+		return "", err
+	}
+	return transcript.Text(segments), nil
+}
+
+// TranscribeSegments transcribes the given audio data and returns the timed
+// segments, including their text tokens. It is safe to call from multiple
+// goroutines.
+func (wa *WhisperAudio) TranscribeSegments(buf []float32) ([]transcript.Segment, error) {
+	return wa.transcriber.Transcribe(buf)
+}
+
+// DetectLanguage returns the languages the model supports, ranked by the
+// probability that the given audio data is spoken in them. See
+// Transcriber.DetectLanguage.
+func (wa *WhisperAudio) DetectLanguage(buf []float32) ([]LanguageProb, error) {
+	return wa.transcriber.DetectLanguage(buf)
+}
+
+// TranscribeWords transcribes the given audio data and returns the timed
+// words. See Transcriber.TranscribeWords.
+func (wa *WhisperAudio) TranscribeWords(buf []float32) ([]transcript.Word, error) {
+	return wa.transcriber.TranscribeWords(buf)
+}
+
+// AlignToRecognition returns the words of text, a known transcript of the
+// given audio data, with their times. See Transcriber.AlignToRecognition.
+func (wa *WhisperAudio) AlignToRecognition(buf []float32, text string) ([]transcript.Word, error) {
+	return wa.transcriber.AlignToRecognition(buf, text)
+}
+
+// SetFilter sets a filter that preprocesses captured audio before it is
+// buffered, or removes it if f is nil. Level metering sees the unfiltered
+// input. To filter audio from other sources, such as files, set the
+// NewFilter field of the Transcriber.
+func (wa *WhisperAudio) SetFilter(f dsp.Filter) {
+	if f == nil {
+		wa.filter.Store(nil)
+		return
 	}
-	return result, nil
+	f.Reset()
+	wa.filter.Store(&f)
+}
+
+// Transcriber returns the Transcriber used by wa.
+func (wa *WhisperAudio) Transcriber() *Transcriber {
+	return wa.transcriber
 }
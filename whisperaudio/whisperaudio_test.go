@@ -0,0 +1,232 @@
+package whisperaudio
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tmc/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+// fakeBackend is an audioBackend that counts initializations and opens
+// fakeStreams.
+type fakeBackend struct {
+	mu                      sync.Mutex
+	initialized, terminated int
+	startErr, readErr       error
+}
+
+func (b *fakeBackend) Initialize() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.initialized++
+	return nil
+}
+
+func (b *fakeBackend) Terminate() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.terminated++
+	return nil
+}
+
+func (b *fakeBackend) OpenInputStream(channels int, sampleRate float64, buf []float32) (inputStream, error) {
+	return &fakeStream{startErr: b.startErr, readErr: b.readErr}, nil
+}
+
+func (b *fakeBackend) Devices() ([]Device, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) counts() (initialized, terminated int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.initialized, b.terminated
+}
+
+// fakeStream is an inputStream that yields silence, or fails with readErr.
+type fakeStream struct {
+	startErr, readErr error
+}
+
+func (s *fakeStream) Start() error { return s.startErr }
+func (s *fakeStream) Stop() error  { return nil }
+func (s *fakeStream) Close() error { return nil }
+
+func (s *fakeStream) Read() error {
+	time.Sleep(time.Millisecond)
+	return s.readErr
+}
+
+// fakeModel is a whisper.Model that counts calls to Close and NewContext,
+// and cannot create contexts.
+type fakeModel struct {
+	closed   int
+	contexts int
+}
+
+func (m *fakeModel) Close() error { m.closed++; return nil }
+func (m *fakeModel) NewContext() (whisper.Context, error) {
+	m.contexts++
+	return nil, errors.New("fake model")
+}
+func (m *fakeModel) IsMultilingual() bool { return false }
+func (m *fakeModel) Languages() []string  { return nil }
+
+// useBackend substitutes b for the audio backend for the rest of the test.
+func useBackend(t *testing.T, b audioBackend) {
+	old := backend
+	backend = b
+	t.Cleanup(func() {
+		backend = old
+		backendRefs = 0
+	})
+}
+
+func newFake(t *testing.T) (*WhisperAudio, *fakeModel) {
+	t.Helper()
+	m := &fakeModel{}
+	wa, err := newWithTranscriber(NewTranscriberFromModel(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wa, m
+}
+
+func TestCloseIdempotent(t *testing.T) {
+	b := &fakeBackend{}
+	useBackend(t, b)
+
+	wa, m := newFake(t)
+	if err := wa.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := wa.Close(); err != nil {
+			t.Fatalf("Close %d: %v", i+1, err)
+		}
+	}
+	if m.closed != 1 {
+		t.Errorf("model closed %d times, want 1", m.closed)
+	}
+	if _, terminated := b.counts(); terminated != 1 {
+		t.Errorf("backend terminated %d times, want 1", terminated)
+	}
+	if err := wa.Start(); err != errClosed {
+		t.Errorf("Start after Close: got %v, want %v", err, errClosed)
+	}
+}
+
+func TestBackendRefCount(t *testing.T) {
+	b := &fakeBackend{}
+	useBackend(t, b)
+
+	wa1, _ := newFake(t)
+	wa2, _ := newFake(t)
+	if initialized, _ := b.counts(); initialized != 1 {
+		t.Fatalf("backend initialized %d times for two instances, want 1", initialized)
+	}
+
+	if err := wa1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, terminated := b.counts(); terminated != 0 {
+		t.Fatalf("backend terminated while an instance is open")
+	}
+	wa1.Close()
+	if _, terminated := b.counts(); terminated != 0 {
+		t.Fatalf("closing an instance twice released the backend twice")
+	}
+
+	if err := wa2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if initialized, terminated := b.counts(); initialized != 1 || terminated != 1 {
+		t.Fatalf("got %d initializations and %d terminations, want 1 and 1", initialized, terminated)
+	}
+
+	// A new instance initializes the backend again.
+	wa3, _ := newFake(t)
+	defer wa3.Close()
+	if initialized, _ := b.counts(); initialized != 2 {
+		t.Fatalf("backend initialized %d times after reopening, want 2", initialized)
+	}
+}
+
+func TestStreamStartError(t *testing.T) {
+	startErr := errors.New("device unavailable")
+	useBackend(t, &fakeBackend{startErr: startErr})
+
+	wa, _ := newFake(t)
+	defer wa.Close()
+	if err := wa.Start(); !errors.Is(err, startErr) {
+		t.Fatalf("Start: got %v, want %v", err, startErr)
+	}
+}
+
+func TestStreamReadError(t *testing.T) {
+	readErr := errors.New("device unplugged")
+	useBackend(t, &fakeBackend{readErr: readErr})
+
+	wa, _ := newFake(t)
+	defer wa.Close()
+	if err := wa.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wa.CollectAudioData(time.Second); !errors.Is(err, readErr) {
+		t.Fatalf("CollectAudioData: got %v, want %v", err, readErr)
+	}
+	select {
+	case ev := <-wa.Events():
+		if !errors.Is(ev.Err, readErr) {
+			t.Errorf("got event %+v, want read error", ev)
+		}
+	default:
+		t.Error("no capture event for the read error")
+	}
+	if err := wa.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnderruns(t *testing.T) {
+	useBackend(t, &fakeBackend{})
+
+	wa, _ := newFake(t)
+	defer wa.Close()
+	buf := make([]float32, bufferSize)
+	if n := wa.Read(buf); n != 0 {
+		t.Fatalf("read %d samples before recording", n)
+	}
+	if err := wa.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wa.CollectAudioData(200 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got := wa.Stats().Underruns; got != 0 {
+		t.Fatalf("got %d underruns from waiting for audio, want 0", got)
+	}
+	wa.ReadAvailable()
+	wa.Read(make([]float32, 10*bufferSize))
+	if got := wa.Stats().Underruns; got != 1 {
+		t.Fatalf("got %d underruns after a short read, want 1", got)
+	}
+}
+
+func TestTranscribeEmpty(t *testing.T) {
+	m := &fakeModel{}
+	tr := NewTranscriberFromModel(m)
+	segments, err := tr.Transcribe(nil)
+	if err != nil || segments != nil {
+		t.Errorf("Transcribe(nil) = %v, %v; want no segments and no error", segments, err)
+	}
+	words, err := tr.TranscribeWords([]float32{})
+	if err != nil || words != nil {
+		t.Errorf("TranscribeWords(empty) = %v, %v; want no words and no error", words, err)
+	}
+	if m.contexts != 0 {
+		t.Errorf("empty audio created %d contexts, want 0", m.contexts)
+	}
+}
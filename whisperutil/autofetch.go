@@ -2,8 +2,12 @@ package whisperutil
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -14,7 +18,12 @@ import (
 const (
 	srcUrl  = "https://huggingface.co/ggerganov/whisper.cpp/resolve/main" // The location of the models
 	srcExt  = ".bin"
+	partExt = ".part"
 	bufSize = 1 << 20 // 1 MB
+
+	progressInterval = 500 * time.Millisecond // How often progress is reported
+	connectTimeout   = 30 * time.Second       // How long to wait for a connection
+	headerTimeout    = time.Minute            // How long to wait for the response headers
 )
 
 // urlForModel returns the URL for the given model on huggingface.co
@@ -31,25 +40,67 @@ func urlForModel(model string) (string, error) {
 	return url.String(), nil
 }
 
-func autoFetch(path, modelName string) error {
-	ctx := context.Background()
+// FetchModel downloads the named model to path, reporting progress to r.
+// If r is nil, no progress is reported. The download is aborted when ctx is
+// cancelled.
+//
+// FetchModel holds a cross-process lock on path while downloading, so
+// concurrent callers wait for the first download and then reuse its result.
+// Models in the table of known models are verified against their published
+// checksums; others, such as quantized variants, are fetched unverified.
+func FetchModel(ctx context.Context, path, modelName string, r ProgressReporter) error {
+	return autoFetch(ctx, path, modelName, r, lockTimeout)
+}
+
+func autoFetch(ctx context.Context, path, modelName string, r ProgressReporter, timeout time.Duration) error {
+	sum := knownModels[baseModelName(modelName)]
 	u, err := urlForModel(modelName)
 	if err != nil {
 		return err
 	}
-	_, err = download(ctx, os.Stderr, u, path)
+	if r == nil {
+		r = ProgressReporterFunc(func(Progress) {})
+	}
+
+	// Create output directory, if needed, so the lock file can be created.
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	lock, err := acquireLock(ctx, path, timeout)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	// Another process may have completed the download while we waited.
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	_, err = download(ctx, r, u, path, sum)
+	if err != nil && !errors.Is(err, ErrChecksumMismatch) && !errors.Is(err, ErrUnknownModel) {
+		return fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
 	return err
 }
 
-// download downloads the model from the given URL to the given output directory
-func download(ctx context.Context, p io.Writer, model, path string) (string, error) {
-	// Create HTTP client
+// download downloads the model from the given URL to the given output directory,
+// verifying that its SHA-1 checksum matches sum unless sum is empty.
+func download(ctx context.Context, r ProgressReporter, model, path, sum string) (string, error) {
+	// Create HTTP client. Only connecting and waiting for the response are
+	// limited in time, since large models take long to transfer on slow
+	// links; ctx cancels the download as a whole.
 	client := http.Client{
-		Timeout: 15 * time.Minute,
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: connectTimeout}).DialContext,
+			TLSHandshakeTimeout:   connectTimeout,
+			ResponseHeaderTimeout: headerTimeout,
+		},
 	}
 
-	// Initiate the download
-	req, err := http.NewRequest("GET", model, nil)
+	// Initiate the download; the request context aborts blocked reads of the
+	// body as soon as ctx is cancelled.
+	req, err := http.NewRequestWithContext(ctx, "GET", model, nil)
 	if err != nil {
 		return "", err
 	}
@@ -58,6 +109,9 @@ func download(ctx context.Context, p io.Writer, model, path string) (string, err
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s: %s", ErrUnknownModel, model, resp.Status)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("%s: %s", model, resp.Status)
 	}
@@ -70,55 +124,71 @@ func download(ctx context.Context, p io.Writer, model, path string) (string, err
 		}
 	}
 
-	// If output file exists and is the same size as the model, skip
-	if info, err := os.Stat(path); err == nil && info.Size() == resp.ContentLength {
-		fmt.Fprintln(p, "Skipping", model, "as it already exists")
-		return "", nil
-	}
-
-	// Create file
-	w, err := os.Create(path)
+	// Create a temporary file which is renamed into place once complete, so
+	// a partial download is never mistaken for the model.
+	w, err := os.CreateTemp(dir, filepath.Base(path)+".*"+partExt)
 	if err != nil {
 		return "", err
 	}
-	defer w.Close()
-
-	// Report
-	fmt.Fprintln(p, "Downloading", model, "to", path)
+	tmpPath := w.Name()
+	defer func() {
+		w.Close()
+		os.Remove(tmpPath)
+	}()
 
 	// Progressively download the model
+	p := Progress{URL: model, Path: path, Total: resp.ContentLength}
+	start := time.Now()
+	last := start
+	report := func(final bool) {
+		p.Final = final
+		if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+			p.Rate = float64(p.Done) / elapsed
+		}
+		if p.Total > 0 && p.Rate > 0 {
+			p.ETA = time.Duration(float64(p.Total-p.Done) / p.Rate * float64(time.Second))
+		}
+		r.ReportProgress(p)
+	}
+	report(false)
+
+	h := sha1.New()
 	data := make([]byte, bufSize)
-	count, pct := int64(0), int64(0)
-	ticker := time.NewTicker(5 * time.Second)
 	for {
-		select {
-		case <-ctx.Done():
-			// Cancelled, return error
-			return path, ctx.Err()
-		case <-ticker.C:
-			pct = downloadReport(p, pct, count, resp.ContentLength)
-		default:
-			// Read body
-			n, err := resp.Body.Read(data)
-			if err != nil {
-				downloadReport(p, pct, count, resp.ContentLength)
-				if err == io.EOF {
-					return path, nil
+		n, err := resp.Body.Read(data)
+		if n > 0 {
+			h.Write(data[:n])
+			m, werr := w.Write(data[:n])
+			p.Done += int64(m)
+			if werr != nil {
+				report(true)
+				return path, fmt.Errorf("failed to write to %s: %w", path, werr)
+			}
+		}
+		if err != nil {
+			report(true)
+			if ctx.Err() != nil {
+				// Cancelled, return the context error
+				return path, ctx.Err()
+			}
+			if err == io.EOF {
+				if err := w.Close(); err != nil {
+					return path, fmt.Errorf("failed to write to %s: %w", path, err)
+				}
+				if got := hex.EncodeToString(h.Sum(nil)); sum != "" && got != sum {
+					return path, fmt.Errorf("%w: %s: got sha1 %s, want %s", ErrChecksumMismatch, model, got, sum)
 				}
-				return path, err
-			} else if m, err := w.Write(data[:n]); err != nil {
-				return path, fmt.Errorf("failed to write to %s: %w", path, err)
-			} else {
-				count += int64(m)
+				// CreateTemp makes files readable only by their owner.
+				if err := os.Chmod(tmpPath, 0644); err != nil {
+					return path, err
+				}
+				return path, os.Rename(tmpPath, path)
 			}
+			return path, err
+		}
+		if time.Since(last) >= progressInterval {
+			last = time.Now()
+			report(false)
 		}
 	}
 }
-
-func downloadReport(w io.Writer, pct, count, total int64) int64 {
-	pct_ := count * 100 / total
-	if pct_ > pct {
-		fmt.Fprintf(w, "  ...%d MB written (%d%%)\n", count/1e6, pct_)
-	}
-	return pct_
-}
@@ -0,0 +1,134 @@
+package whisperutil
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// bundleDirName is the cache subdirectory holding models materialized from
+// an fs.FS or io.Reader.
+const bundleDirName = "bundled"
+
+// MaterializeModel writes the model read from r to disk, so that it can be
+// loaded by whisper.cpp, and returns its path. Files are named by the size
+// and name of the model, so materializing the same model again reuses the
+// existing file without reading r when its size is known up front, and
+// files left by other versions of the model are removed. The model is
+// written under the cache directory, or under the system temporary
+// directory if the cache is not writable.
+func MaterializeModel(r io.Reader, name string) (string, error) {
+	dir, err := bundleDir()
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Base(name)
+	size := readerSize(r)
+	if size >= 0 {
+		dst := bundlePath(dir, base, size)
+		if info, err := os.Stat(dst); err == nil && info.Size() == size {
+			return dst, nil
+		}
+	}
+
+	tmp, err := os.CreateTemp(dir, "model-*"+partExt)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not write model %s: %w", name, err)
+	}
+	if size >= 0 && n != size {
+		return "", fmt.Errorf("could not write model %s: read %d of %d bytes", name, n, size)
+	}
+
+	dst := bundlePath(dir, base, n)
+	if info, err := os.Stat(dst); err == nil && info.Size() == n {
+		return dst, nil
+	}
+	if _, err := InspectModel(tmp.Name()); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", err
+	}
+	removeSuperseded(dir, base, dst)
+	return dst, nil
+}
+
+// MaterializeModelFS is like MaterializeModel but reads the model from the
+// named file in fsys, such as an embed.FS.
+func MaterializeModelFS(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return MaterializeModel(f, path.Base(name))
+}
+
+// bundleDir returns a writable directory for materialized models.
+func bundleDir() (string, error) {
+	if cd, err := CacheDir(); err == nil {
+		dir := filepath.Join(cd, bundleDirName)
+		if err := os.MkdirAll(dir, 0755); err == nil {
+			return dir, nil
+		}
+	}
+	dir := filepath.Join(os.TempDir(), CacheDirName, bundleDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("could not create model directory: %w", err)
+	}
+	return dir, nil
+}
+
+// bundlePath returns the path of the materialized model with the given name
+// and size.
+func bundlePath(dir, name string, size int64) string {
+	return filepath.Join(dir, strconv.FormatInt(size, 10)+"-"+name)
+}
+
+// removeSuperseded removes materialized models with the given name other
+// than keep, which are left by other versions of the model.
+func removeSuperseded(dir, name, keep string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		prefix, ok := strings.CutSuffix(e.Name(), "-"+name)
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, err := strconv.ParseInt(prefix, 10, 64); err != nil {
+			continue
+		}
+		if path := filepath.Join(dir, e.Name()); path != keep {
+			os.Remove(path)
+		}
+	}
+}
+
+// readerSize returns the number of bytes left in r, or -1 if it is not
+// known without reading r.
+func readerSize(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Len() int }: // bytes.Reader, strings.Reader
+		return int64(r.Len())
+	case interface{ Stat() (fs.FileInfo, error) }: // os.File, fs.File
+		if info, err := r.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	}
+	return -1
+}
@@ -0,0 +1,136 @@
+package whisperutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// usedExt is the extension of the marker files whose modification time
+// records when a cached model was last used.
+const usedExt = ".used"
+
+// CachePolicy limits the disk space used by the model cache.
+type CachePolicy struct {
+	// MaxSize is the maximum total size of cached models in bytes. Zero
+	// means no limit.
+	MaxSize int64
+	// Keep lists model names that are never evicted.
+	Keep []string
+}
+
+// CachedModel describes a model in the cache.
+type CachedModel struct {
+	Name     string    // The model file name, e.g. "ggml-base.en.bin"
+	Path     string    // The path to the model file
+	Size     int64     // The size of the model file in bytes
+	LastUsed time.Time // When the model was last resolved by GetModelPath
+}
+
+// CachedModels returns the models in the cache, least recently used first.
+func CachedModels() ([]CachedModel, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var models []CachedModel
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != srcExt {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		m := CachedModel{
+			Name:     e.Name(),
+			Path:     filepath.Join(dir, e.Name()),
+			Size:     info.Size(),
+			LastUsed: info.ModTime(),
+		}
+		if used, err := os.Stat(m.Path + usedExt); err == nil {
+			m.LastUsed = used.ModTime()
+		}
+		models = append(models, m)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].LastUsed.Before(models[j].LastUsed)
+	})
+	return models, nil
+}
+
+// Prune evicts least recently used models until the cache fits within the
+// policy, and returns the evicted models. Models that are being fetched are
+// never evicted.
+func Prune(policy CachePolicy) ([]CachedModel, error) {
+	if policy.MaxSize <= 0 {
+		return nil, nil
+	}
+	models, err := CachedModels()
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, m := range models {
+		total += m.Size
+	}
+
+	var removed []CachedModel
+	for _, m := range models {
+		if total <= policy.MaxSize {
+			break
+		}
+		if policy.keeps(m.Name) {
+			continue
+		}
+		lock, err := tryLock(m.Path)
+		if err != nil || lock == nil {
+			continue
+		}
+		err = os.Remove(m.Path)
+		if err == nil {
+			os.Remove(m.Path + usedExt)
+			os.Remove(m.Path + lockExt)
+		}
+		lock.release()
+		if err != nil {
+			return removed, fmt.Errorf("could not evict %s: %w", m.Name, err)
+		}
+		total -= m.Size
+		removed = append(removed, m)
+	}
+	return removed, nil
+}
+
+// keeps reports whether the policy protects the named model.
+func (p CachePolicy) keeps(name string) bool {
+	for _, k := range p.Keep {
+		if k == name || baseModelName(k) == baseModelName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// markUsed records that the model at path was used, if it is in the cache.
+func markUsed(path string) {
+	dir, err := CacheDir()
+	if err != nil || !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return
+	}
+	now := time.Now()
+	if err := os.Chtimes(path+usedExt, now, now); os.IsNotExist(err) {
+		if f, err := os.Create(path + usedExt); err == nil {
+			f.Close()
+		}
+	}
+}
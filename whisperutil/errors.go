@@ -0,0 +1,45 @@
+package whisperutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrModelNotFound is returned when the model file does not exist in any
+	// of the searched locations and auto-fetching is disabled.
+	ErrModelNotFound = errors.New("model not found")
+
+	// ErrUnknownModel is returned when the model host has no model of the
+	// requested name, so it cannot be fetched.
+	ErrUnknownModel = errors.New("unknown model name")
+
+	// ErrChecksumMismatch is returned when a downloaded model does not match
+	// its published checksum.
+	ErrChecksumMismatch = errors.New("model checksum mismatch")
+
+	// ErrDownloadFailed is returned when a model could not be downloaded.
+	ErrDownloadFailed = errors.New("model download failed")
+)
+
+// ModelError records a failure to resolve a model and the locations that
+// were searched for it.
+type ModelError struct {
+	Model    string   // The model name
+	Searched []string // The paths that were searched, in order
+	Err      error    // The underlying error
+}
+
+func (e *ModelError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %v", e.Model, e.Err)
+	if len(e.Searched) > 0 {
+		fmt.Fprintf(&b, " (searched %s)", strings.Join(e.Searched, ", "))
+	}
+	return b.String()
+}
+
+func (e *ModelError) Unwrap() error {
+	return e.Err
+}
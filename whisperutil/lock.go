@@ -0,0 +1,92 @@
+package whisperutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	lockExt          = ".lock"
+	lockPollInterval = 250 * time.Millisecond
+	lockTimeout      = 30 * time.Minute // Default time to wait for another process's download
+)
+
+// ErrLockTimeout is returned when the lock for a model could not be acquired
+// before the lock timeout expired.
+var ErrLockTimeout = errors.New("timed out waiting for model lock")
+
+// fileLock is an advisory, cross-process lock on a lock file next to the
+// file it protects. It is an operating system file lock, which is released
+// when its holder exits, so a process that dies never leaves a lock behind.
+// The lock file itself is left in place.
+type fileLock struct {
+	f *os.File
+}
+
+// acquireLock takes the lock for path, waiting up to timeout for another
+// holder to release it.
+func acquireLock(ctx context.Context, path string, timeout time.Duration) (*fileLock, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		l, err := tryLock(path)
+		if err != nil || l != nil {
+			return l, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s: %w", path+lockExt, ErrLockTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// tryLock takes the lock for path if it is free, and returns nil if another
+// holder has it. Prune removes the lock files of the models it evicts, so a
+// lock taken on a file that has since been removed is retried on the file
+// now in its place.
+func tryLock(path string) (*fileLock, error) {
+	for {
+		f, err := os.OpenFile(path+lockExt, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("could not open lock file: %w", err)
+		}
+		ok, err := lockFile(f)
+		if err != nil || !ok {
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("could not lock %s: %w", f.Name(), err)
+			}
+			return nil, nil
+		}
+		if current(f) {
+			return &fileLock{f: f}, nil
+		}
+		unlockFile(f)
+		f.Close()
+	}
+}
+
+// current reports whether f is still the file at its name.
+func current(f *os.File) bool {
+	held, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	named, err := os.Stat(f.Name())
+	return err == nil && os.SameFile(held, named)
+}
+
+// release releases the lock.
+func (l *fileLock) release() error {
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,11 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package whisperutil
+
+import "os"
+
+// lockFile always succeeds: file locks are not supported on this platform,
+// so concurrent fetches are not coordinated.
+func lockFile(f *os.File) (bool, error) { return true, nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,290 @@
+package whisperutil
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ggmlMagic is the magic number at the start of a ggml whisper model file.
+const ggmlMagic = 0x67676d6c // "ggml"
+
+// ggmlQntVersionFactor separates the quantization version from the file type.
+const ggmlQntVersionFactor = 1000
+
+// ErrInvalidModel is returned when a file is not a valid ggml whisper model.
+var ErrInvalidModel = errors.New("invalid ggml whisper model")
+
+// ModelInfo describes the hyperparameters of a ggml whisper model.
+type ModelInfo struct {
+	Path string // The file the model was read from, if any
+	Size int64  // The size of the file in bytes, if known
+
+	Vocab       int // Vocabulary size
+	AudioCtx    int // Audio context length
+	AudioState  int // Audio encoder width
+	AudioHead   int // Audio encoder attention heads
+	AudioLayers int // Audio encoder layers
+	TextCtx     int // Text context length
+	TextState   int // Text decoder width
+	TextHead    int // Text decoder attention heads
+	TextLayers  int // Text decoder layers
+	Mels        int // Number of mel bins
+	FType       int // ggml file type, including the quantization version
+
+	Tensors int // Number of tensors, only set by InspectModel
+}
+
+// Multilingual reports whether the model supports languages other than
+// English.
+func (mi *ModelInfo) Multilingual() bool {
+	return mi.Vocab >= 51865
+}
+
+// SizeClass returns the model size class, e.g. "base" or "large", based on
+// the number of encoder layers.
+func (mi *ModelInfo) SizeClass() string {
+	switch mi.AudioLayers {
+	case 4:
+		return "tiny"
+	case 6:
+		return "base"
+	case 12:
+		return "small"
+	case 24:
+		return "medium"
+	case 32:
+		return "large"
+	}
+	return "unknown"
+}
+
+// Quantization returns the name of the weight type, e.g. "f16" or "q5_0".
+func (mi *ModelInfo) Quantization() string {
+	switch mi.FType % ggmlQntVersionFactor {
+	case 0:
+		return "f32"
+	case 1:
+		return "f16"
+	case 2:
+		return "q4_0"
+	case 3:
+		return "q4_1"
+	case 7:
+		return "q8_0"
+	case 8:
+		return "q5_0"
+	case 9:
+		return "q5_1"
+	}
+	return fmt.Sprintf("ftype(%d)", mi.FType)
+}
+
+func (mi *ModelInfo) String() string {
+	lang := "english-only"
+	if mi.Multilingual() {
+		lang = "multilingual"
+	}
+	return fmt.Sprintf("%s %s %s (vocab=%d audio_ctx=%d audio_layers=%d text_ctx=%d text_layers=%d mels=%d)",
+		mi.SizeClass(), lang, mi.Quantization(),
+		mi.Vocab, mi.AudioCtx, mi.AudioLayers, mi.TextCtx, mi.TextLayers, mi.Mels)
+}
+
+// ReadModelInfo reads the header of a ggml whisper model from r.
+func ReadModelInfo(r io.Reader) (*ModelInfo, error) {
+	var hdr struct {
+		Magic  uint32
+		Params [11]int32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return nil, fmt.Errorf("%w: could not read header: %v", ErrInvalidModel, err)
+	}
+	if hdr.Magic != ggmlMagic {
+		return nil, fmt.Errorf("%w: bad magic %#x", ErrInvalidModel, hdr.Magic)
+	}
+	p := hdr.Params
+	mi := &ModelInfo{
+		Vocab:       int(p[0]),
+		AudioCtx:    int(p[1]),
+		AudioState:  int(p[2]),
+		AudioHead:   int(p[3]),
+		AudioLayers: int(p[4]),
+		TextCtx:     int(p[5]),
+		TextState:   int(p[6]),
+		TextHead:    int(p[7]),
+		TextLayers:  int(p[8]),
+		Mels:        int(p[9]),
+		FType:       int(p[10]),
+	}
+	for _, v := range p[:10] {
+		if v <= 0 {
+			return nil, fmt.Errorf("%w: bad hyperparameters %v", ErrInvalidModel, p)
+		}
+	}
+	return mi, nil
+}
+
+// InspectModel reads and validates the ggml whisper model at path. Beyond
+// the header it walks the mel filters, vocabulary and tensor table, so that
+// truncated or corrupt files are rejected without loading the weights. The
+// tensor data is seeked over rather than read.
+func InspectModel(path string) (*ModelInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &modelReader{f: f, br: bufio.NewReader(f)}
+	mi, err := ReadModelInfo(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	mi.Path, mi.Size = path, info.Size()
+	if err := skipModelBody(r, mi, info.Size()); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return mi, nil
+}
+
+// skipModelBody reads past the mel filters, vocabulary and tensors that
+// follow the header, counting the tensors into mi.
+func skipModelBody(r *modelReader, mi *ModelInfo, size int64) error {
+	truncated := func(what string, err error) error {
+		return fmt.Errorf("%w: truncated %s at offset %d: %v", ErrInvalidModel, what, r.n, err)
+	}
+	readInt := func() (int64, error) {
+		var v int32
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return int64(v), err
+	}
+	skip := func(n int64) error {
+		if n < 0 || r.n+n > size {
+			return io.ErrUnexpectedEOF
+		}
+		return r.skip(n)
+	}
+
+	// Mel filters
+	nMel, err := readInt()
+	if err != nil {
+		return truncated("mel filters", err)
+	}
+	nFFT, err := readInt()
+	if err != nil {
+		return truncated("mel filters", err)
+	}
+	if err := skip(nMel * nFFT * 4); err != nil {
+		return truncated("mel filters", err)
+	}
+
+	// Vocabulary
+	nVocab, err := readInt()
+	if err != nil {
+		return truncated("vocabulary", err)
+	}
+	for i := int64(0); i < nVocab; i++ {
+		n, err := readInt()
+		if err != nil {
+			return truncated("vocabulary", err)
+		}
+		if err := skip(n); err != nil {
+			return truncated("vocabulary", err)
+		}
+	}
+
+	// Tensors
+	for r.n < size {
+		var th struct{ Dims, NameLen, Type int32 }
+		if err := binary.Read(r, binary.LittleEndian, &th); err != nil {
+			return truncated("tensor header", err)
+		}
+		if th.Dims < 1 || th.Dims > 4 {
+			return fmt.Errorf("%w: bad tensor dimensions %d at offset %d", ErrInvalidModel, th.Dims, r.n)
+		}
+		elems := int64(1)
+		for i := int32(0); i < th.Dims; i++ {
+			n, err := readInt()
+			if err != nil {
+				return truncated("tensor header", err)
+			}
+			elems *= n
+		}
+		if err := skip(int64(th.NameLen)); err != nil {
+			return truncated("tensor name", err)
+		}
+		n, err := tensorSize(int(th.Type), elems)
+		if err != nil {
+			return err
+		}
+		if err := skip(n); err != nil {
+			return truncated("tensor data", err)
+		}
+		mi.Tensors++
+	}
+	if mi.Tensors == 0 {
+		return fmt.Errorf("%w: no tensors", ErrInvalidModel)
+	}
+	return nil
+}
+
+// tensorSize returns the size in bytes of a tensor of the given ggml type.
+func tensorSize(typ int, elems int64) (int64, error) {
+	// block size and bytes per block for each ggml type
+	var blk, sz int64
+	switch typ {
+	case 0: // f32
+		blk, sz = 1, 4
+	case 1: // f16
+		blk, sz = 1, 2
+	case 2: // q4_0
+		blk, sz = 32, 18
+	case 3: // q4_1
+		blk, sz = 32, 20
+	case 6: // q5_0
+		blk, sz = 32, 22
+	case 7: // q5_1
+		blk, sz = 32, 24
+	case 8: // q8_0
+		blk, sz = 32, 34
+	default:
+		return 0, fmt.Errorf("%w: unknown tensor type %d", ErrInvalidModel, typ)
+	}
+	return elems / blk * sz, nil
+}
+
+// modelReader reads a model file through a buffer, counting the bytes
+// consumed, and seeks over data it skips.
+type modelReader struct {
+	f  io.ReadSeeker
+	br *bufio.Reader
+	n  int64
+}
+
+func (mr *modelReader) Read(p []byte) (int, error) {
+	n, err := mr.br.Read(p)
+	mr.n += int64(n)
+	return n, err
+}
+
+// skip skips the next n bytes.
+func (mr *modelReader) skip(n int64) error {
+	if buffered := int64(mr.br.Buffered()); n > buffered {
+		if _, err := mr.f.Seek(n-buffered, io.SeekCurrent); err != nil {
+			return err
+		}
+		mr.br.Reset(mr.f)
+		mr.n += n
+		return nil
+	}
+	_, err := mr.br.Discard(int(n))
+	mr.n += n
+	return err
+}
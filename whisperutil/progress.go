@@ -0,0 +1,85 @@
+package whisperutil
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Progress describes the state of an in-flight model download.
+type Progress struct {
+	URL   string        // The URL being fetched
+	Path  string        // The destination path
+	Done  int64         // Bytes written so far
+	Total int64         // Total bytes, or -1 if unknown
+	Rate  float64       // Average transfer rate in bytes per second
+	ETA   time.Duration // Estimated time remaining, or 0 if unknown
+	Final bool          // True for the last report of a download
+}
+
+// Percent returns the completed percentage, or -1 if the total is unknown.
+func (p Progress) Percent() int64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return p.Done * 100 / p.Total
+}
+
+// ProgressReporter receives progress updates while a model is downloaded.
+type ProgressReporter interface {
+	ReportProgress(p Progress)
+}
+
+// ProgressReporterFunc adapts a function to the ProgressReporter interface.
+type ProgressReporterFunc func(Progress)
+
+// ReportProgress calls f(p).
+func (f ProgressReporterFunc) ReportProgress(p Progress) {
+	f(p)
+}
+
+// WriterProgress is a ProgressReporter that prints human readable progress
+// lines to a writer.
+type WriterProgress struct {
+	W        io.Writer
+	Interval time.Duration // Minimum time between lines (default 5s)
+
+	last    time.Time
+	lastPct int64
+	started bool
+}
+
+// NewWriterProgress returns a ProgressReporter that prints to w.
+func NewWriterProgress(w io.Writer) *WriterProgress {
+	return &WriterProgress{W: w, Interval: 5 * time.Second}
+}
+
+// ReportProgress implements ProgressReporter.
+func (wp *WriterProgress) ReportProgress(p Progress) {
+	if !wp.started {
+		wp.started = true
+		wp.last = time.Now()
+		wp.lastPct = 0
+		fmt.Fprintln(wp.W, "Downloading", p.URL, "to", p.Path)
+	}
+	if !p.Final && time.Since(wp.last) < wp.Interval {
+		return
+	}
+	pct := p.Percent()
+	if !p.Final && pct >= 0 && pct <= wp.lastPct {
+		return
+	}
+	wp.last, wp.lastPct = time.Now(), pct
+	switch {
+	case pct < 0:
+		fmt.Fprintf(wp.W, "  ...%d MB written (%.1f MB/s)\n", p.Done/1e6, p.Rate/1e6)
+	case p.Final:
+		fmt.Fprintf(wp.W, "  ...%d MB written (%d%%)\n", p.Done/1e6, pct)
+	default:
+		fmt.Fprintf(wp.W, "  ...%d MB written (%d%%, %.1f MB/s, %v remaining)\n",
+			p.Done/1e6, pct, p.Rate/1e6, p.ETA.Round(time.Second))
+	}
+	if p.Final {
+		wp.started = false
+	}
+}
@@ -0,0 +1,127 @@
+package whisperutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/shirou/gopsutil/mem"
+)
+
+// AutoModelName is the model name that selects a model with RecommendModel.
+const AutoModelName = "auto"
+
+// DefaultTargetRTF is the default target real-time factor: transcribing one
+// second of audio should take at most half a second.
+const DefaultTargetRTF = 0.5
+
+// ErrNoSuitableModel is returned when no model fits the given requirements.
+var ErrNoSuitableModel = errors.New("no model fits the available resources")
+
+// modelProfile describes the resource needs of a model size class.
+type modelProfile struct {
+	name   string
+	memory uint64  // Approximate memory needed to run the model, in bytes
+	cost   float64 // Compute cost relative to the tiny model
+}
+
+// modelProfiles lists the model size classes from smallest to largest.
+var modelProfiles = []modelProfile{
+	{"tiny", 390 << 20, 1},
+	{"base", 500 << 20, 2},
+	{"small", 1 << 30, 6},
+	{"medium", 2600 << 20, 16},
+	{"large-v2", 4700 << 20, 32},
+}
+
+// tinyRTFPerCore is the approximate real-time factor of the tiny model on a
+// single core.
+const tinyRTFPerCore = 0.2
+
+// maxUsefulThreads is the number of threads beyond which whisper.cpp does
+// not get meaningfully faster.
+const maxUsefulThreads = 8
+
+// ModelRequirements describes the machine and constraints used to select a
+// model.
+type ModelRequirements struct {
+	// Memory is the memory available for the model in bytes. If zero, the
+	// available system memory is used.
+	Memory uint64
+	// CPUs is the number of CPU cores to use. If zero, runtime.NumCPU is
+	// used.
+	CPUs int
+	// Multilingual requires a model that supports languages other than
+	// English.
+	Multilingual bool
+	// TargetRTF is the largest acceptable real-time factor. If zero,
+	// DefaultTargetRTF is used.
+	TargetRTF float64
+	// Benchmark, if set, measures the real-time factor of a model file.
+	// It is used instead of the built-in estimate for candidate models
+	// that are already present in the cache.
+	Benchmark func(modelPath string) (float64, error)
+}
+
+// Recommendation is a model selected by RecommendModel.
+type Recommendation struct {
+	ModelName string  // The model name, e.g. "base.en"
+	RTF       float64 // The estimated or measured real-time factor
+	Measured  bool    // True if RTF was measured by a benchmark
+}
+
+// RecommendModel returns the largest model that fits in memory and is
+// expected to transcribe faster than the target real-time factor.
+func RecommendModel(req ModelRequirements) (Recommendation, error) {
+	if req.Memory == 0 {
+		vm, err := mem.VirtualMemory()
+		if err != nil {
+			return Recommendation{}, fmt.Errorf("could not get available memory: %w", err)
+		}
+		req.Memory = vm.Available
+	}
+	if req.CPUs <= 0 {
+		req.CPUs = runtime.NumCPU()
+	}
+	if req.TargetRTF <= 0 {
+		req.TargetRTF = DefaultTargetRTF
+	}
+	threads := req.CPUs
+	if threads > maxUsefulThreads {
+		threads = maxUsefulThreads
+	}
+	cacheDir, _ := CacheDir()
+
+	var best *Recommendation
+	for _, p := range modelProfiles {
+		if p.memory > req.Memory {
+			break
+		}
+		name := p.name
+		if !req.Multilingual && name != "large-v2" {
+			name += ".en"
+		}
+		rec := Recommendation{
+			ModelName: name,
+			RTF:       tinyRTFPerCore * p.cost / float64(threads),
+		}
+		if req.Benchmark != nil && cacheDir != "" {
+			path := filepath.Join(cacheDir, "ggml-"+name+srcExt)
+			if _, err := os.Stat(path); err == nil {
+				if rtf, err := req.Benchmark(path); err == nil {
+					rec.RTF, rec.Measured = rtf, true
+				}
+			}
+		}
+		if rec.RTF > req.TargetRTF {
+			break
+		}
+		best = &rec
+	}
+	if best == nil {
+		return Recommendation{}, ErrNoSuitableModel
+	}
+	return *best, nil
+}
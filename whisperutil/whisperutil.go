@@ -1,10 +1,14 @@
 package whisperutil
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
@@ -16,15 +20,47 @@ const (
 type ModelPathOptions struct {
 	ModelName string
 	AutoFetch bool
+	Progress  ProgressReporter
+
+	// SearchPaths are directories searched for the model before the
+	// cache directory.
+	SearchPaths []string
+
+	// ModelFS and ModelReader, if set, supply the model directly instead of
+	// searching for ModelName. The model is materialized on disk with
+	// MaterializeModel.
+	ModelFS     fs.FS
+	ModelReader io.Reader
+
+	// Requirements are used to select a model when ModelName is
+	// AutoModelName.
+	Requirements ModelRequirements
+	// OnSelect, if set, is called with the model selected when ModelName
+	// is AutoModelName.
+	OnSelect func(Recommendation)
+
+	// CachePolicy is applied to the cache after a model is fetched.
+	CachePolicy CachePolicy
+	// NoMarkUsed leaves the record of when a cached model was last used
+	// unchanged, for callers that only look at the model.
+	NoMarkUsed bool
+
+	// LockTimeout is how long to wait for another process that is
+	// fetching the same model.
+	LockTimeout time.Duration
 }
 
 // Option is a function that configures a ModelPathOptions.
 type Option func(*ModelPathOptions)
 
-// WithModelName sets the model name to use.
+// WithModelName sets the model name to use. The name AutoModelName selects
+// a model suited to this machine with RecommendModel.
 func WithModelName(modelName string) Option {
 	return func(mpo *ModelPathOptions) {
 		mpo.ModelName = modelName
+		if modelName == AutoModelName {
+			return
+		}
 		// add prefix and suffix if not present
 		if !strings.HasPrefix(mpo.ModelName, "ggml-") {
 			mpo.ModelName = "ggml-" + mpo.ModelName
@@ -42,28 +78,167 @@ func WithAutoFetch() Option {
 	}
 }
 
+// WithSearchPaths adds directories that are searched for the model before
+// the cache directory.
+func WithSearchPaths(dirs ...string) Option {
+	return func(mpo *ModelPathOptions) {
+		mpo.SearchPaths = append(mpo.SearchPaths, dirs...)
+	}
+}
+
+// WithModelFS loads the model from the named file in fsys, such as an
+// embed.FS, instead of searching for it. This allows a binary to ship with
+// its model.
+func WithModelFS(fsys fs.FS, name string) Option {
+	return func(mpo *ModelPathOptions) {
+		mpo.ModelFS = fsys
+		mpo.ModelName = name
+	}
+}
+
+// WithModelReader loads the model from r instead of searching for it. The
+// name is used to name the materialized file.
+func WithModelReader(r io.Reader, name string) Option {
+	return func(mpo *ModelPathOptions) {
+		mpo.ModelReader = r
+		mpo.ModelName = name
+	}
+}
+
+// WithModelRequirements sets the requirements used to select a model when
+// the model name is AutoModelName.
+func WithModelRequirements(req ModelRequirements) Option {
+	return func(mpo *ModelPathOptions) {
+		mpo.Requirements = req
+	}
+}
+
+// WithOnSelect sets a function that is called with the model selected when
+// the model name is AutoModelName.
+func WithOnSelect(f func(Recommendation)) Option {
+	return func(mpo *ModelPathOptions) {
+		mpo.OnSelect = f
+	}
+}
+
+// WithProgress sets the reporter that receives download progress when the
+// model is auto-fetched. Passing nil disables progress reporting.
+func WithProgress(r ProgressReporter) Option {
+	return func(mpo *ModelPathOptions) {
+		mpo.Progress = r
+	}
+}
+
+// WithLockTimeout sets how long to wait for another process that is already
+// fetching the same model before giving up with ErrLockTimeout.
+func WithLockTimeout(d time.Duration) Option {
+	return func(mpo *ModelPathOptions) {
+		mpo.LockTimeout = d
+	}
+}
+
+// WithCachePolicy sets the policy used to prune the model cache after a
+// model is fetched.
+func WithCachePolicy(p CachePolicy) Option {
+	return func(mpo *ModelPathOptions) {
+		mpo.CachePolicy = p
+	}
+}
+
+// WithoutMarkUsed resolves the model without recording that it was used,
+// so looking at a model does not protect it from Prune.
+func WithoutMarkUsed() Option {
+	return func(mpo *ModelPathOptions) {
+		mpo.NoMarkUsed = true
+	}
+}
+
 // GetModelPath returns the path to the model file.
+//
+// If the model cannot be found or fetched, the returned error is a
+// *ModelError wrapping one of ErrModelNotFound, ErrUnknownModel,
+// ErrChecksumMismatch or ErrDownloadFailed.
 func GetModelPath(opts ...Option) (string, error) {
+	return GetModelPathContext(context.Background(), opts...)
+}
+
+// GetModelPathContext is like GetModelPath but uses ctx to cancel any
+// download started by auto-fetching.
+func GetModelPathContext(ctx context.Context, opts ...Option) (string, error) {
 	options := ModelPathOptions{
-		ModelName: DefaultModelName, // Default model name
-		AutoFetch: false,            // Default AutoFetch
+		ModelName: DefaultModelName,             // Default model name
+		AutoFetch: false,                        // Default AutoFetch
+		Progress:  NewWriterProgress(os.Stderr), // Default progress reporter
+
+		LockTimeout: lockTimeout,
 	}
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	cd, err := os.UserCacheDir()
+	switch {
+	case options.ModelFS != nil:
+		return MaterializeModelFS(options.ModelFS, options.ModelName)
+	case options.ModelReader != nil:
+		return MaterializeModel(options.ModelReader, options.ModelName)
+	}
+
+	if options.ModelName == AutoModelName {
+		rec, err := RecommendModel(options.Requirements)
+		if err != nil {
+			return "", &ModelError{Model: options.ModelName, Err: err}
+		}
+		if options.OnSelect != nil {
+			options.OnSelect(rec)
+		}
+		WithModelName(rec.ModelName)(&options)
+	}
+
+	cacheDir, err := CacheDir()
 	if err != nil {
-		return "", fmt.Errorf("could not get user cache directory: %w", err)
+		return "", err
 	}
-	path := filepath.Join(cd, CacheDirName, options.ModelName)
 
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		if options.AutoFetch {
-			fmt.Fprintln(os.Stderr, "Model not found, trying to fetch it...")
-			// not implemented ddy et
-			return path, autoFetch(path, options.ModelName)
+	// Search the configured directories, then the cache.
+	var searched []string
+	for _, dir := range append(options.SearchPaths, cacheDir) {
+		path := filepath.Join(dir, options.ModelName)
+		searched = append(searched, path)
+		if _, err := os.Stat(path); err == nil {
+			if !options.NoMarkUsed {
+				markUsed(path)
+			}
+			return path, nil
 		}
 	}
+
+	if !options.AutoFetch {
+		return "", &ModelError{Model: options.ModelName, Searched: searched, Err: ErrModelNotFound}
+	}
+	fmt.Fprintln(os.Stderr, "Model not found, trying to fetch it...")
+	path := filepath.Join(cacheDir, options.ModelName)
+	if err := autoFetch(ctx, path, options.ModelName, options.Progress, options.LockTimeout); err != nil {
+		return "", &ModelError{Model: options.ModelName, Searched: searched, Err: err}
+	}
+	markUsed(path)
+
+	policy := options.CachePolicy
+	policy.Keep = append(policy.Keep[:len(policy.Keep):len(policy.Keep)], options.ModelName)
+	removed, err := Prune(policy)
+	for _, m := range removed {
+		fmt.Fprintln(os.Stderr, "Evicted", m.Name, "from the model cache")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not prune the model cache:", err)
+	}
 	return path, nil
 }
+
+// CacheDir returns the directory in which models are cached.
+func CacheDir() (string, error) {
+	cd, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user cache directory: %w", err)
+	}
+	return filepath.Join(cd, CacheDirName), nil
+}